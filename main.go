@@ -1,12 +1,18 @@
 package main
 
 import (
+	"bufio"
 	"context"
 	_ "embed"
 	"errors"
+	"flag"
 	"fmt"
 	"log"
 	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
 
 	"github.com/jackc/pgx/v5"
 
@@ -16,12 +22,31 @@ import (
 //go:embed monarch/help/help.txt
 var helpText string
 
+//go:embed monarch/help/help.migrate.txt
+var helpMigrateText string
+
 //go:embed monarch/help/help.create.txt
 var helpCreateText string
 
 //go:embed monarch/help/help.reapply.txt
 var helpReapplyText string
 
+//go:embed monarch/help/help.rollback.txt
+var helpRollbackText string
+
+//go:embed monarch/help/help.redo.txt
+var helpRedoText string
+
+//go:embed monarch/help/help.baseline.txt
+var helpBaselineText string
+
+//go:embed monarch/help/help.mark.txt
+var helpMarkText string
+
+// migrationTimestamp is the layout of the timestamp migration names start
+// with.
+const migrationTimestamp = "20060102150405"
+
 func main() {
 	if err := run(os.Args); err != nil {
 		log.Fatal(err)
@@ -29,8 +54,36 @@ func main() {
 }
 
 func run(args []string) error {
-	if len(args) < 2 {
+	globalFlags := flag.NewFlagSet("monarch", flag.ExitOnError)
+	globalFlags.Usage = func() {
 		fmt.Println(helpText)
+		fmt.Println("Flags:")
+		globalFlags.PrintDefaults()
+	}
+	lockTimeout := globalFlags.Duration(
+		"lock-timeout",
+		envDuration("MONARCH_LOCK_TIMEOUT", monarch.DefaultLockTimeout),
+		"how long to wait for another run to release the migration lock; 0 waits indefinitely (env MONARCH_LOCK_TIMEOUT)",
+	)
+	table := globalFlags.String(
+		"table",
+		envString("MONARCH_TABLE", monarch.DefaultTableName),
+		"name of the table migrations are recorded in (env MONARCH_TABLE)",
+	)
+	schema := globalFlags.String(
+		"schema",
+		os.Getenv("MONARCH_SCHEMA"),
+		"schema of the table migrations are recorded in; defaults to the search_path (env MONARCH_SCHEMA)",
+	)
+	deployID := globalFlags.String(
+		"deploy-id",
+		os.Getenv("MONARCH_DEPLOY_ID"),
+		"identifier of the deploy, such as a build number, recorded with migrations (env MONARCH_DEPLOY_ID)",
+	)
+	args = append([]string{args[0]}, parseFlags(globalFlags, args[1:])...)
+
+	if len(args) < 2 {
+		globalFlags.Usage()
 		os.Exit(1)
 	}
 
@@ -43,42 +96,342 @@ func run(args []string) error {
 		return errors.New("provide a migrations path via MIGRATIONS_PATH env var")
 	}
 
-	db, err := pgx.Connect(context.Background(), databaseURL)
-	if err != nil {
-		return err
-	}
+	ctx := context.Background()
+	newMigrator := func(opts ...monarch.Option) *monarch.Migrator {
+		db, err := pgx.Connect(ctx, databaseURL)
+		if err != nil {
+			log.Fatal(err)
+		}
 
-	migrator, err := monarch.NewMigrator(db, migrationsPath)
-	if err != nil {
-		log.Fatal(err)
+		opts = append(
+			opts,
+			monarch.WithLockTimeout(*lockTimeout),
+			monarch.WithTable(*table),
+			monarch.WithSchema(*schema),
+			monarch.WithDeployID(*deployID),
+		)
+		migrator, err := monarch.NewMigrator(db, migrationsPath, opts...)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		return migrator
 	}
 
 	command := args[1]
+	flags := flag.NewFlagSet(command, flag.ExitOnError)
 	switch command {
 	case "init":
-		if len(args) > 2 {
-			log.Fatalf("unexpected parameter %s", args[2])
+		params := parseFlags(flags, args[2:])
+		if len(params) > 0 {
+			log.Fatalf("unexpected parameter %s", params[0])
 		}
-		return migrator.InitDirectory()
+		return newMigrator().InitDirectory()
 	case "migrate":
-		if len(args) > 2 {
-			log.Fatalf("unexpected parameter %s", args[2])
+		flags.Usage = commandUsage(flags, helpMigrateText)
+		singleTransaction := flags.Bool("single-transaction", false, "run every pending migration in one transaction")
+		checksumMismatch := flags.String("checksum-mismatch", "error", "what to do when a migrated file changed: error, warn or ignore")
+		to := flags.String("to", "", "only migrate up to and including this migration timestamp or name")
+		outOfOrder := flags.String("out-of-order", "warn", "what to do with pending migrations older than the newest migrated one: error, warn or allow")
+		params := parseFlags(flags, args[2:])
+		if len(params) > 0 {
+			log.Fatalf("unexpected parameter %s", params[0])
+		}
+
+		var opts []monarch.Option
+		if *singleTransaction {
+			opts = append(opts, monarch.WithTransactionMode(monarch.TransactionPerBatch))
+		}
+		switch *checksumMismatch {
+		case "error":
+			opts = append(opts, monarch.WithChecksumPolicy(monarch.ChecksumError))
+		case "warn":
+			opts = append(opts, monarch.WithChecksumPolicy(monarch.ChecksumWarn))
+		case "ignore":
+			opts = append(opts, monarch.WithChecksumPolicy(monarch.ChecksumIgnore))
+		default:
+			log.Fatalf("unknown --checksum-mismatch value %s", *checksumMismatch)
+		}
+		switch *outOfOrder {
+		case "error":
+			opts = append(opts, monarch.WithOutOfOrderPolicy(monarch.OutOfOrderError))
+		case "warn":
+			opts = append(opts, monarch.WithOutOfOrderPolicy(monarch.OutOfOrderWarn))
+		case "allow":
+			opts = append(opts, monarch.WithOutOfOrderPolicy(monarch.OutOfOrderAllow))
+		default:
+			log.Fatalf("unknown --out-of-order value %s", *outOfOrder)
+		}
+		if *to != "" {
+			return newMigrator(opts...).MigrateTo(ctx, *to)
+		}
+		return newMigrator(opts...).Migrate(ctx)
+	case "status":
+		params := parseFlags(flags, args[2:])
+		if len(params) > 0 {
+			log.Fatalf("unexpected parameter %s", params[0])
+		}
+		statuses, err := newMigrator().Status(ctx)
+		if err != nil {
+			return err
+		}
+		return printStatus(statuses)
+	case "verify":
+		strict := flags.Bool("strict", false, "also fail when a migration has no recorded checksum")
+		params := parseFlags(flags, args[2:])
+		if len(params) > 0 {
+			log.Fatalf("unexpected parameter %s", params[0])
+		}
+		problems, err := newMigrator().Verify(ctx)
+		if err != nil {
+			return err
+		}
+		return printVerification(problems, *strict)
+	case "plan":
+		params := parseFlags(flags, args[2:])
+		if len(params) > 0 {
+			log.Fatalf("unexpected parameter %s", params[0])
 		}
-		return migrator.Migrate(context.Background())
+		planned, err := newMigrator().Plan(ctx)
+		if err != nil {
+			return err
+		}
+		printPlan(planned)
+		return nil
 	case "create":
-		if len(args) != 3 {
+		sql := flags.Bool("sql", false, "create a .sql migration instead of a Lua migration")
+		params := parseFlags(flags, args[2:])
+		if len(params) != 1 {
 			fmt.Println(helpCreateText)
 			os.Exit(1)
 		}
-		return migrator.Create(args[2])
+		if *sql {
+			return newMigrator().CreateSQL(params[0])
+		}
+		return newMigrator().Create(params[0])
 	case "reapply":
-		if len(args) != 3 {
+		params := parseFlags(flags, args[2:])
+		if len(params) != 1 {
 			fmt.Println(helpReapplyText)
 			os.Exit(1)
 		}
-		return migrator.Reapply(context.Background(), args[2])
+		return newMigrator().Reapply(ctx, params[0])
+	case "rollback":
+		params := parseFlags(flags, args[2:])
+		if len(params) > 1 {
+			fmt.Println(helpRollbackText)
+			os.Exit(1)
+		}
+		if len(params) == 0 {
+			return newMigrator().Rollback(ctx, 1)
+		}
+		// a migration's timestamp is all digits too, but is never a step count
+		if steps, err := strconv.Atoi(params[0]); err == nil && len(params[0]) != len(migrationTimestamp) {
+			return newMigrator().Rollback(ctx, steps)
+		}
+		return newMigrator().RollbackTo(ctx, params[0])
+	case "redo":
+		params := parseFlags(flags, args[2:])
+		if len(params) > 1 {
+			fmt.Println(helpRedoText)
+			os.Exit(1)
+		}
+		if len(params) == 0 {
+			return newMigrator().Redo(ctx, 1)
+		}
+		steps, err := strconv.Atoi(params[0])
+		if err != nil {
+			fmt.Println(helpRedoText)
+			os.Exit(1)
+		}
+		return newMigrator().Redo(ctx, steps)
+	case "baseline":
+		params := parseFlags(flags, args[2:])
+		if len(params) != 1 {
+			fmt.Println(helpBaselineText)
+			os.Exit(1)
+		}
+		return newMigrator().Baseline(ctx, params[0])
+	case "mark-applied", "mark-pending":
+		yes := flags.Bool("yes", false, "do not ask for confirmation")
+		params := parseFlags(flags, args[2:])
+		if len(params) != 1 {
+			fmt.Println(helpMarkText)
+			os.Exit(1)
+		}
+
+		if command == "mark-applied" {
+			if !*yes && !confirm(fmt.Sprintf("mark %s as applied without running it?", params[0])) {
+				return errors.New("aborted")
+			}
+			return newMigrator().MarkApplied(ctx, params[0])
+		}
+
+		if !*yes && !confirm(fmt.Sprintf("mark %s as pending without rolling it back?", params[0])) {
+			return errors.New("aborted")
+		}
+		return newMigrator().MarkPending(ctx, params[0])
 	default:
 		log.Fatalf("unknown command %s", command)
 		return nil
 	}
 }
+
+// confirm asks a yes or no question on stdin, defaulting to no.
+func confirm(question string) bool {
+	fmt.Printf("%s [y/N] ", question)
+
+	answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil {
+		return false
+	}
+
+	answer = strings.ToLower(strings.TrimSpace(answer))
+
+	return answer == "y" || answer == "yes"
+}
+
+// commandUsage prints a command's help text followed by its flags.
+func commandUsage(flags *flag.FlagSet, help string) func() {
+	return func() {
+		fmt.Println(help)
+		fmt.Println("Flags:")
+		flags.PrintDefaults()
+	}
+}
+
+// parseFlags parses a command's flags and returns its remaining parameters.
+func parseFlags(flags *flag.FlagSet, args []string) []string {
+	// flag.ExitOnError exits on errors instead of returning them
+	_ = flags.Parse(args)
+
+	return flags.Args()
+}
+
+// envString reads an env var to use as a flag's default.
+func envString(key string, fallback string) string {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+
+	return value
+}
+
+// envDuration reads a duration from an env var to use as a flag's default.
+func envDuration(key string, fallback time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		log.Fatalf("invalid duration in %s: %s", key, err)
+	}
+
+	return duration
+}
+
+func printStatus(statuses []monarch.MigrationStatus) error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "MIGRATION\tSTATE\tMIGRATED AT\tLAST APPLIED AT\tDURATION\tDEPLOY ID")
+	for _, status := range statuses {
+		_, _ = fmt.Fprintf(
+			w,
+			"%s\t%s\t%s\t%s\t%s\t%s\n",
+			status.Name,
+			formatState(status),
+			formatTime(status.MigratedAt),
+			formatTime(status.LastAppliedAt),
+			formatDuration(status.Duration),
+			formatString(status.DeployID),
+		)
+	}
+
+	return w.Flush()
+}
+
+func printVerification(problems []monarch.MigrationVerification, strict bool) error {
+	if len(problems) == 0 {
+		fmt.Println("every migrated migration matches its file")
+		return nil
+	}
+
+	failed := 0
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "MIGRATION\tPROBLEM")
+	for _, problem := range problems {
+		_, _ = fmt.Fprintf(w, "%s\t%s\n", problem.Name, problem.Problem)
+		if problem.Problem != monarch.VerificationUnknown || strict {
+			failed++
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d migrations failed verification", failed)
+	}
+
+	return nil
+}
+
+func printPlan(planned []monarch.PlannedMigration) {
+	if len(planned) == 0 {
+		fmt.Println("-- no pending migrations")
+		return
+	}
+
+	for _, migration := range planned {
+		fmt.Printf("-- %s\n", migration.Name)
+		if migration.NotRun {
+			fmt.Println("-- not run because it opts out of transactions; review the migration itself")
+		}
+
+		for _, statement := range migration.Statements {
+			fmt.Printf("%s;\n", strings.TrimSpace(statement.SQL))
+			for i, arg := range statement.Args {
+				if s, isString := arg.(string); isString {
+					fmt.Printf("--   $%d = %q\n", i+1, s)
+				} else {
+					fmt.Printf("--   $%d = %v\n", i+1, arg)
+				}
+			}
+		}
+		fmt.Println()
+	}
+}
+
+func formatState(status monarch.MigrationStatus) string {
+	if status.AppliedVia != "" && status.AppliedVia != monarch.AppliedViaMigrate {
+		return fmt.Sprintf("%s (%s)", status.State, status.AppliedVia)
+	}
+
+	return string(status.State)
+}
+
+func formatDuration(d time.Duration) string {
+	if d == 0 {
+		return "-"
+	}
+
+	return d.String()
+}
+
+func formatString(s string) string {
+	if s == "" {
+		return "-"
+	}
+
+	return s
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+
+	return t.Local().Format(time.DateTime)
+}
@@ -0,0 +1,74 @@
+package monarch
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Baseline records every migration up to and including name as migrated
+// without running it, for adopting monarch on a database whose schema was
+// built by another tool. Baselined migrations are recorded with
+// AppliedViaBaseline.
+func (m *Migrator) Baseline(ctx context.Context, name string) error {
+	return m.withLock(ctx, func() error {
+		return m.baseline(ctx, name)
+	})
+}
+
+func (m *Migrator) baseline(ctx context.Context, name string) error {
+	err := m.upgradeBookkeeping(ctx)
+	if err != nil {
+		return err
+	}
+
+	files, err := m.files.getMigrationFiles()
+	if err != nil {
+		return err
+	}
+
+	files, err = filesUpTo(files, name)
+	if err != nil {
+		return err
+	}
+
+	var baselined []string
+	err = m.inTransaction(ctx, func(tx pgx.Tx) error {
+		model := m.modelFor(tx)
+		for _, file := range files {
+			isMigrated, err := m.model.IsMigrated(ctx, file)
+			if err != nil {
+				return err
+			}
+			if isMigrated {
+				continue
+			}
+
+			checksum, err := m.files.checksum(file)
+			if err != nil {
+				return err
+			}
+
+			err = model.MarkAsMigrated(ctx, file, m.newRecord(AppliedViaBaseline, checksum))
+			if err != nil {
+				return err
+			}
+
+			baselined = append(baselined, file)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, file := range baselined {
+		m.model.remember(file)
+		fmt.Printf("baselined %s\n", file)
+	}
+	fmt.Printf("baselined %d migrations\n", len(baselined))
+
+	return nil
+}
@@ -0,0 +1,171 @@
+package monarch
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// bookkeepingSteps upgrade the migrations table from one version to the
+// next, where the step at index i upgrades version i to version i+1. %[1]s
+// is the migrations table and %[2]s is its audit table.
+//
+// Steps are only ever appended, never changed, so every install goes through
+// the same steps. They must also tolerate running against tables created
+// before versions were recorded, when every step is run again.
+var bookkeepingSteps = []string{
+	`
+		CREATE TABLE IF NOT EXISTS %[1]s (
+			migration_id varchar PRIMARY KEY,
+			migrated_at timestamptz DEFAULT NOW() NOT NULL,
+			last_applied_at timestamptz DEFAULT NOW() NOT NULL
+		);
+	`,
+	`
+		ALTER TABLE %[1]s ADD COLUMN IF NOT EXISTS checksum varchar;
+	`,
+	`
+		ALTER TABLE %[1]s
+			ADD COLUMN IF NOT EXISTS duration_ms bigint,
+			ADD COLUMN IF NOT EXISTS applied_by varchar,
+			ADD COLUMN IF NOT EXISTS hostname varchar,
+			ADD COLUMN IF NOT EXISTS monarch_version varchar,
+			ADD COLUMN IF NOT EXISTS deploy_id varchar,
+			ADD COLUMN IF NOT EXISTS reapply_count integer DEFAULT 0 NOT NULL;
+		-- the default is set separately so migrations recorded before the
+		-- column existed are not attributed to whoever ran the upgrade
+		ALTER TABLE %[1]s ALTER COLUMN applied_by SET DEFAULT current_user;
+	`,
+	`
+		ALTER TABLE %[1]s ADD COLUMN IF NOT EXISTS applied_via varchar DEFAULT 'migrate' NOT NULL;
+	`,
+	`
+		CREATE TABLE IF NOT EXISTS %[2]s (
+			audit_id bigserial PRIMARY KEY,
+			migration_id varchar NOT NULL,
+			action varchar NOT NULL,
+			db_user varchar DEFAULT current_user NOT NULL,
+			os_user varchar,
+			hostname varchar,
+			monarch_version varchar,
+			deploy_id varchar,
+			performed_at timestamptz DEFAULT NOW() NOT NULL
+		);
+	`,
+}
+
+// Upgrade creates the migrations table, or upgrades it to the layout this
+// version of monarch expects. It should run in a transaction while holding
+// the advisory lock.
+func (model *model) Upgrade(ctx context.Context) error {
+	if model.table.schema != "" {
+		_, err := model.db.Exec(
+			ctx,
+			fmt.Sprintf(
+				`CREATE SCHEMA IF NOT EXISTS %s`,
+				pgx.Identifier{model.table.schema}.Sanitize(),
+			),
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err := model.db.Exec(
+		ctx,
+		fmt.Sprintf(
+			`
+				CREATE TABLE IF NOT EXISTS %s (
+					version integer NOT NULL,
+					upgraded_at timestamptz DEFAULT NOW() NOT NULL
+				);
+			`,
+			model.table.meta().sanitize(),
+		),
+	)
+	if err != nil {
+		return err
+	}
+
+	version, err := model.Version(ctx)
+	if err != nil {
+		return err
+	}
+
+	if version > len(bookkeepingSteps) {
+		return fmt.Errorf(
+			"%s was upgraded by a newer version of monarch (version %d; this version only knows up to %d); upgrade monarch",
+			model.table.sanitize(),
+			version,
+			len(bookkeepingSteps),
+		)
+	}
+
+	if version == len(bookkeepingSteps) {
+		return nil
+	}
+
+	for _, step := range bookkeepingSteps[version:] {
+		_, err = model.db.Exec(
+			ctx,
+			fmt.Sprintf(step, model.table.sanitize(), model.table.audit().sanitize()),
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = model.db.Exec(
+		ctx,
+		fmt.Sprintf(
+			`
+				DELETE FROM %[1]s;
+				INSERT INTO %[1]s (version) VALUES (%[2]d);
+			`,
+			model.table.meta().sanitize(),
+			len(bookkeepingSteps),
+		),
+	)
+
+	return err
+}
+
+// Version returns the version of the migrations table's layout, which is 0
+// when it has not been created or was created before versions were
+// recorded.
+func (model *model) Version(ctx context.Context) (int, error) {
+	var exists bool
+	err := model.db.QueryRow(
+		ctx,
+		`
+			SELECT to_regclass($1) IS NOT NULL
+		`,
+		model.table.meta().sanitize(),
+	).Scan(&exists)
+	if err != nil || !exists {
+		return 0, err
+	}
+
+	var version int
+	err = model.db.QueryRow(
+		ctx,
+		fmt.Sprintf(
+			`
+				SELECT COALESCE(MAX(version), 0)
+				FROM %s
+			`,
+			model.table.meta().sanitize(),
+		),
+	).Scan(&version)
+
+	return version, err
+}
+
+// IsUpToDate reports whether the migrations table exists with the layout
+// this version of monarch expects.
+func (model *model) IsUpToDate(ctx context.Context) (bool, error) {
+	version, err := model.Version(ctx)
+
+	return version == len(bookkeepingSteps), err
+}
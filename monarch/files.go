@@ -1,12 +1,16 @@
 package monarch
 
 import (
+	"bufio"
+	"crypto/sha256"
 	_ "embed"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"io/fs"
 	"os"
 	"path"
 	"regexp"
-	"sort"
 	"strings"
 	"text/template"
 	"time"
@@ -18,31 +22,71 @@ import (
 //go:embed template.lua.tmpl
 var defaultMigrationTemplate string
 
-var regexpMigMatchFileName = regexp.MustCompile("[0-9]{14}.*\\.lua")
+//go:embed template.sql.tmpl
+var defaultSQLMigrationTemplate string
+
+var regexpMigMatchFileName = regexp.MustCompile("^[0-9]{14}.*\\.(lua|sql)$")
 var regexpMigMatchUnderscore = regexp.MustCompile("(_+)([a-zA-Z0-9])")
 
+// directiveNoTransaction, as a comment at the top of a migration file, runs
+// the migration directly on the connection instead of within a transaction.
+const directiveNoTransaction = "monarch:no-transaction"
+
+// directiveDown, as a comment on its own line in a .sql migration, separates
+// the statements that migrate from the statements that roll back.
+const directiveDown = "monarch:down"
+
+// timestampFormat is the format of the timestamp migration names start with.
+const timestampFormat = "20060102150405"
+
+const (
+	extLua = ".lua"
+	extSQL = ".sql"
+)
+
+type migrationDirectives struct {
+	noTransaction bool
+}
+
+var errReadOnlyFS = errors.New("migrations loaded from an fs.FS are read-only; use a migrations directory to create migrations")
+
 type files struct {
+	// directory is where new files are written; it is empty when migrations
+	// are loaded from an fs.FS, such as an embedded one, that cannot be
+	// written to
 	directory string
+	fsys      fs.FS
+}
+
+func newDirectoryFiles(dir string) *files {
+	return &files{directory: dir, fsys: os.DirFS(dir)}
+}
+
+func newFSFiles(fsys fs.FS) *files {
+	return &files{fsys: fsys}
 }
 
-func (f *files) templateFilePath() string {
-	return path.Join(f.directory, "template.lua.tmpl")
+func templateFileName(ext string) string {
+	return "template" + ext + ".tmpl"
 }
 
 func (f *files) validateDirectory() error {
-	dir := f.directory
-	fi, err := os.Stat(dir)
+	fi, err := fs.Stat(f.fsys, ".")
 	if err != nil {
 		return err
 	}
 	if !fi.IsDir() {
-		return fmt.Errorf("%s is not a directory", dir)
+		return fmt.Errorf("%s is not a directory", f.directory)
 	}
 
 	return nil
 }
 
 func (f *files) initDirectory() error {
+	if f.directory == "" {
+		return errReadOnlyFS
+	}
+
 	dir := f.directory
 	fi, err := os.Stat(dir)
 	if os.IsNotExist(err) {
@@ -56,35 +100,41 @@ func (f *files) initDirectory() error {
 		return fmt.Errorf("'%s' is not a directory", dir)
 	}
 
-	templateFile := f.templateFilePath()
-	_, err = os.Stat(templateFile)
-	if os.IsNotExist(err) {
-		err = os.WriteFile(templateFile, []byte(defaultMigrationTemplate), 0644)
-	} else if err != nil {
-		return err
+	for ext, template := range map[string]string{
+		extLua: defaultMigrationTemplate,
+		extSQL: defaultSQLMigrationTemplate,
+	} {
+		templateFile := path.Join(dir, templateFileName(ext))
+		_, err = os.Stat(templateFile)
+		if os.IsNotExist(err) {
+			err = os.WriteFile(templateFile, []byte(template), 0644)
+		}
+		if err != nil {
+			return err
+		}
 	}
 
 	return nil
 }
 
-func (f *files) createNewMigrationFile(name string) error {
-	datetime := time.Now().UTC().Format("20060102150405")
+func (f *files) createNewMigrationFile(name string, ext string) error {
+	if f.directory == "" {
+		return errReadOnlyFS
+	}
+
+	datetime := time.Now().UTC().Format(timestampFormat)
 	migrationName := datetime + "_" + toCamelCase(name)
-	fileName := path.Join(f.directory, migrationName+".lua")
+	fileName := path.Join(f.directory, migrationName+ext)
 
 	_, err := os.Stat(fileName)
 	if !os.IsNotExist(err) {
 		return fmt.Errorf("file %s already exists", fileName)
 	}
 
-	templateFilePath := f.templateFilePath()
-	_, err = os.Stat(templateFilePath)
-	if err != nil {
-		return fmt.Errorf("template file %s not found", templateFilePath)
-	}
-	migrationTemplate, err := os.ReadFile(templateFilePath)
+	templateFilePath := path.Join(f.directory, templateFileName(ext))
+	migrationTemplate, err := fs.ReadFile(f.fsys, templateFileName(ext))
 	if err != nil {
-		return err
+		return fmt.Errorf("template file %s not found; run `monarch init` to create it", templateFilePath)
 	}
 
 	file, err := os.Create(fileName)
@@ -114,34 +164,108 @@ func (f *files) createNewMigrationFile(name string) error {
 }
 
 func (f *files) getMigrationFiles() ([]string, error) {
-	fi, err := os.Stat(f.directory)
+	// entries are sorted by name, which sorts migrations by their timestamp
+	entries, err := fs.ReadDir(f.fsys, ".")
 	if err != nil {
 		return nil, err
 	}
-	if !fi.IsDir() {
-		return nil, err
+
+	files := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if !entry.IsDir() && regexpMigMatchFileName.MatchString(entry.Name()) {
+			files = append(files, entry.Name())
+		}
 	}
-	d, err := os.Open(f.directory)
+
+	return files, nil
+}
+
+// readDirectives reads the directives from the comments at the top of a
+// migration file, stopping at the first line that is not a comment.
+func (f *files) readDirectives(name string) (migrationDirectives, error) {
+	var directives migrationDirectives
+
+	file, err := f.fsys.Open(name)
 	if err != nil {
-		return nil, err
+		return directives, err
 	}
-	defer d.Close()
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
 
-	names, _ := d.Readdirnames(-1)
-	sort.Strings(names)
+		comment, isComment := strings.CutPrefix(line, "--")
+		if !isComment {
+			break
+		}
 
-	files := make([]string, 0, len(names))
-	for _, n := range names {
-		if regexpMigMatchFileName.Match([]byte(n)) {
-			files = append(files, n)
+		if strings.TrimSpace(comment) == directiveNoTransaction {
+			directives.noTransaction = true
 		}
 	}
 
-	return files, nil
+	return directives, scanner.Err()
+}
+
+// checksum returns the hex encoded SHA-256 of a migration file's contents.
+func (f *files) checksum(name string) (string, error) {
+	contents, err := fs.ReadFile(f.fsys, name)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(contents)
+
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// matchesName reports whether target, the base name of a migration argument,
+// is the migration name, the name without its extension, or its timestamp.
+func matchesName(name string, target string) bool {
+	return name == target ||
+		strings.TrimSuffix(name, path.Ext(name)) == target ||
+		(len(name) >= len(timestampFormat) && name[:len(timestampFormat)] == target)
 }
 
-func (f *files) migrationPath(name string) string {
-	return path.Join(f.directory, name)
+// resolveName turns a migration's timestamp, its name or a path to it into
+// the name of a file within the migrations directory, finding the file's
+// extension when it was left off.
+func (f *files) resolveName(name string) (string, error) {
+	migrationName := path.Base(name)
+
+	files, err := f.getMigrationFiles()
+	if err != nil {
+		return "", err
+	}
+
+	for _, file := range files {
+		if matchesName(file, migrationName) {
+			return file, nil
+		}
+	}
+
+	ext := path.Ext(migrationName)
+	if ext != extLua && ext != extSQL {
+		ext = extLua
+		_, err := fs.Stat(f.fsys, migrationName+extSQL)
+		if err == nil {
+			ext = extSQL
+		}
+		migrationName = migrationName + ext
+	}
+
+	if !regexpMigMatchFileName.MatchString(migrationName) {
+		return "", fmt.Errorf(
+			"migration file %s does not exist",
+			migrationName,
+		)
+	}
+
+	return migrationName, nil
 }
 
 func toCamelCase(name string) string {
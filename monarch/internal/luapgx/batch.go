@@ -0,0 +1,197 @@
+package luapgx
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	lua "github.com/yuin/gopher-lua"
+)
+
+const defaultBatchSize = 1000
+
+// dbBatchUpdate runs a statement over and over, each time in its own short
+// transaction, until it affects no rows, so large tables can be backfilled
+// without holding locks for the whole backfill.
+//
+// The statement is given the batch size as $1 and must affect at most that
+// many rows, which lets max_batches tell a final, smaller batch from one that
+// stopped with rows left to update. When an after key is given,
+// the statement is also given the greatest key of the previous batch as $2,
+// starting from after, and must return the key as its first column. The key
+// is printed with each batch so an interrupted backfill can be resumed from
+// it. The total number of rows affected and the last key are returned.
+func dbBatchUpdate(s *session) func(*lua.LState) int {
+	return func(L *lua.LState) int {
+		options := L.CheckTable(1)
+
+		sql, ok := options.RawGetString("sql").(lua.LString)
+		if !ok {
+			L.ArgError(1, "sql is required")
+			return 0
+		}
+
+		batchSize := optionInt(L, options, "batch_size", defaultBatchSize)
+		if batchSize < 1 {
+			L.ArgError(1, "batch_size must be at least 1")
+			return 0
+		}
+		sleep := time.Duration(optionInt(L, options, "sleep_ms", 0)) * time.Millisecond
+		maxBatches := optionInt(L, options, "max_batches", 0)
+		key := options.RawGetString("after")
+		keyed := key != lua.LNil
+
+		if inTransaction(s.db) {
+			L.RaiseError("db.batch_update runs each batch in its own transaction; add a `-- monarch:no-transaction` comment to the top of the migration")
+			return 0
+		}
+
+		beginner, ok := s.db.(Beginner)
+		if !ok {
+			L.RaiseError("transactions are not supported by this connection")
+			return 0
+		}
+
+		var total int64
+		fmt.Println()
+		for batch := 1; ; batch++ {
+			affected, last := runBatch(L, s, beginner, string(sql), batchArgs(L, batchSize, keyed, key), keyed)
+			total += affected
+
+			if keyed && last != lua.LNil {
+				key = last
+				fmt.Printf("  batch %d updated %d rows (%d in total), last key %s\n", batch, affected, total, lua.LVAsString(key))
+			} else {
+				fmt.Printf("  batch %d updated %d rows (%d in total)\n", batch, affected, total)
+			}
+
+			if affected == 0 {
+				break
+			}
+
+			if maxBatches > 0 && batch == maxBatches {
+				// the statement is limited to batch_size rows, so a smaller
+				// batch updated the last of them
+				if affected < int64(batchSize) {
+					break
+				}
+
+				L.RaiseError("stopped after %d batches with rows left to update", maxBatches)
+				return 0
+			}
+
+			if sleep > 0 {
+				select {
+				case <-s.ctx.Done():
+					L.RaiseError(s.ctx.Err().Error())
+					return 0
+				case <-time.After(sleep):
+				}
+			}
+		}
+
+		L.Push(lua.LNumber(total))
+		L.Push(key)
+
+		return 2
+	}
+}
+
+// batchArgs returns the parameters of a batch: the batch size and, when
+// keyed, the greatest key of the previous batch.
+func batchArgs(L *lua.LState, batchSize int, keyed bool, key lua.LValue) []any {
+	args := []any{batchSize}
+	if !keyed {
+		return args
+	}
+
+	arg, err := encodeParam(key)
+	if err != nil {
+		L.ArgError(1, fmt.Sprintf("after: %s", err.Error()))
+		return nil
+	}
+
+	return append(args, arg)
+}
+
+// runBatch runs one batch of db.batch_update in its own transaction and
+// returns the number of rows it affected and, when keyed, the greatest key it
+// returned.
+func runBatch(L *lua.LState, s *session, beginner Beginner, sql string, args []any, keyed bool) (int64, lua.LValue) {
+	tx, err := beginner.Begin(s.ctx)
+	if err != nil {
+		L.RaiseError(err.Error())
+		return 0, lua.LNil
+	}
+	// a no-op once committed, and rolls the batch back when an error is raised
+	defer tx.Rollback(s.ctx)
+
+	rows, err := tx.Query(s.ctx, sql, args...)
+	if err != nil {
+		L.RaiseError(err.Error())
+		return 0, lua.LNil
+	}
+	defer rows.Close()
+
+	var last lua.LValue = lua.LNil
+	for rows.Next() {
+		if !keyed {
+			continue
+		}
+
+		value := newRowTable(L, rows).RawGetInt(1)
+		if last == lua.LNil || L.LessThan(last, value) {
+			last = value
+		}
+	}
+
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		L.RaiseError(err.Error())
+		return 0, lua.LNil
+	}
+
+	affected := rows.CommandTag().RowsAffected()
+	if keyed && affected > 0 && last == lua.LNil {
+		L.RaiseError("db.batch_update with an after key requires the statement to return the key as its first column")
+		return 0, lua.LNil
+	}
+
+	err = tx.Commit(s.ctx)
+	if err != nil {
+		L.RaiseError(err.Error())
+		return 0, lua.LNil
+	}
+
+	return affected, last
+}
+
+// inTransaction reports whether statements run through db run in a
+// transaction.
+func inTransaction(db Querier) bool {
+	switch db := db.(type) {
+	case pgx.Tx:
+		return true
+	case *Recorder:
+		return inTransaction(db.db)
+	default:
+		return false
+	}
+}
+
+// optionInt returns the integer field name of an options table, or def when
+// it is not set.
+func optionInt(L *lua.LState, options *lua.LTable, name string, def int) int {
+	value := options.RawGetString(name)
+	if value == lua.LNil {
+		return def
+	}
+
+	number, ok := value.(lua.LNumber)
+	if !ok {
+		L.ArgError(1, fmt.Sprintf("%s must be a number", name))
+		return 0
+	}
+
+	return int(number)
+}
@@ -13,42 +13,35 @@ type Querier interface {
 	Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error)
 }
 
-func dbExec(ctx context.Context, db Querier) func(*lua.LState) int {
-	return func(L *lua.LState) int {
-		sql := L.CheckString(1)
-		paramsTable := L.OptTable(2, L.NewTable())
+// Beginner is a Querier that can open transactions, or savepoints when it is
+// already a transaction, for db.transaction.
+type Beginner interface {
+	Querier
+	Begin(ctx context.Context) (pgx.Tx, error)
+}
 
-		var paramsSlice []interface{}
-		paramsTable.ForEach(func(i lua.LValue, v lua.LValue) {
-			paramsSlice = append(paramsSlice, v)
-		})
+// dbExec runs a statement and returns the number of rows it affected and its
+// command tag, such as "UPDATE 500".
+func dbExec(s *session) func(*lua.LState) int {
+	return func(L *lua.LState) int {
+		sql, args := statement(L)
 
-		_, err := db.Exec(ctx, sql, paramsSlice...)
+		tag, err := s.db.Exec(s.ctx, sql, args...)
 		if err != nil {
 			L.RaiseError(err.Error())
 			return 0
 		}
 
-		return 0
+		L.Push(lua.LNumber(tag.RowsAffected()))
+		L.Push(lua.LString(tag.String()))
+
+		return 2
 	}
 }
 
-func dbQuery(ctx context.Context, db Querier) func(*lua.LState) int {
+func dbQuery(s *session) func(*lua.LState) int {
 	return func(L *lua.LState) int {
-		sql := L.CheckString(1)
-		paramsTable := L.OptTable(2, L.NewTable())
-
-		var paramsSlice []interface{}
-		paramsTable.ForEach(func(i lua.LValue, v lua.LValue) {
-			paramsSlice = append(paramsSlice, v)
-		})
-
-		rows, err := db.Query(ctx, sql, paramsSlice...)
-		if err != nil {
-			defer rows.Close()
-			L.RaiseError(err.Error())
-			return 0
-		}
+		rows := query(L, s)
 
 		columns := rows.FieldDescriptions()
 		columnCount := len(columns)
@@ -61,36 +54,7 @@ func dbQuery(ctx context.Context, db Querier) func(*lua.LState) int {
 				return 0
 			}
 
-			values, err := rows.Values()
-			if err != nil {
-				rows.Close()
-				L.RaiseError(err.Error())
-				return 0
-			}
-
-			rowTable := iter.CreateTable(columnCount, columnCount)
-			for c, value := range values {
-				column := columns[c]
-				columnName := column.Name
-				columnIndex := c + 1
-
-				lVal, err := pgxToLuaValue(column.DataTypeOID, value)
-				if isUnknownColumnTypeError(err) {
-					rows.Close()
-					return raiseUnknownColumnTypeError(iter, columnIndex, columnName, value)
-				} else if err != nil {
-					rows.Close()
-					L.RaiseError(err.Error())
-					return 0
-				}
-
-				if lVal != nil {
-					iter.SetField(rowTable, columnName, lVal)
-					rowTable.Insert(columnIndex, lVal)
-				}
-			}
-
-			iter.Push(rowTable)
+			iter.Push(newRowTable(iter, rows))
 
 			return 1
 		}))
@@ -112,6 +76,216 @@ func dbQuery(ctx context.Context, db Querier) func(*lua.LState) int {
 	}
 }
 
+// dbQueryOne returns the only row of a query, and raises an error when the
+// query returns no rows or more than one.
+func dbQueryOne(s *session) func(*lua.LState) int {
+	return func(L *lua.LState) int {
+		rows := query(L, s)
+		defer rows.Close()
+
+		if !rows.Next() {
+			rows.Close()
+			if err := rows.Err(); err != nil {
+				L.RaiseError(err.Error())
+				return 0
+			}
+
+			L.RaiseError("query returned no rows; expected exactly one")
+			return 0
+		}
+
+		rowTable := newRowTable(L, rows)
+
+		if rows.Next() {
+			rows.Close()
+			L.RaiseError("query returned more than one row; expected exactly one")
+			return 0
+		}
+
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			L.RaiseError(err.Error())
+			return 0
+		}
+
+		L.Push(rowTable)
+
+		return 1
+	}
+}
+
+// dbQueryAll returns every row of a query as an array.
+func dbQueryAll(s *session) func(*lua.LState) int {
+	return func(L *lua.LState) int {
+		rows := query(L, s)
+		defer rows.Close()
+
+		resultTable := L.NewTable()
+		for rows.Next() {
+			resultTable.Append(newRowTable(L, rows))
+		}
+
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			L.RaiseError(err.Error())
+			return 0
+		}
+
+		L.Push(resultTable)
+
+		return 1
+	}
+}
+
+// dbScalar returns the first column of the first row of a query, or nil when
+// the query returns no rows.
+func dbScalar(s *session) func(*lua.LState) int {
+	return func(L *lua.LState) int {
+		rows := query(L, s)
+		defer rows.Close()
+
+		var value lua.LValue = lua.LNil
+		if rows.Next() {
+			value = newRowTable(L, rows).RawGetInt(1)
+		}
+
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			L.RaiseError(err.Error())
+			return 0
+		}
+
+		L.Push(value)
+
+		return 1
+	}
+}
+
+// query runs the query given by the SQL and params arguments of a db
+// function.
+func query(L *lua.LState, s *session) pgx.Rows {
+	sql, args := statement(L)
+
+	rows, err := s.db.Query(s.ctx, sql, args...)
+	if err != nil {
+		defer rows.Close()
+		L.RaiseError(err.Error())
+		return nil
+	}
+
+	return rows
+}
+
+// statement returns the SQL and arguments given by the SQL and params
+// arguments of a db function.
+func statement(L *lua.LState) (string, []any) {
+	sql := L.CheckString(1)
+	paramsTable := L.OptTable(2, L.NewTable())
+
+	sql, args, err := bindParams(sql, paramsTable)
+	if err != nil {
+		L.ArgError(2, err.Error())
+		return "", nil
+	}
+
+	return sql, args
+}
+
+// newRowTable converts the current row to a table of its values by column
+// name and by position. rows is closed before raising an error.
+func newRowTable(L *lua.LState, rows pgx.Rows) *lua.LTable {
+	columns := rows.FieldDescriptions()
+	columnCount := len(columns)
+
+	values, err := rows.Values()
+	if err != nil {
+		rows.Close()
+		L.RaiseError(err.Error())
+		return nil
+	}
+
+	rowTable := L.CreateTable(columnCount, columnCount)
+	for c, value := range values {
+		column := columns[c]
+		columnName := column.Name
+		columnIndex := c + 1
+
+		lVal, err := pgxToLuaValue(L, column.DataTypeOID, value)
+		if isUnknownColumnTypeError(err) {
+			rows.Close()
+			raiseUnknownColumnTypeError(L, columnIndex, columnName, value)
+			return nil
+		} else if err != nil {
+			rows.Close()
+			L.RaiseError(err.Error())
+			return nil
+		}
+
+		if lVal != nil {
+			L.SetField(rowTable, columnName, lVal)
+			rowTable.Insert(columnIndex, lVal)
+		}
+	}
+
+	return rowTable
+}
+
+// dbTransaction calls a function within a transaction, or a savepoint when
+// already in one, that is committed when the function returns and rolled
+// back when it raises an error. The error is raised again after rolling back
+// so it can be handled with pcall.
+func dbTransaction(s *session) func(*lua.LState) int {
+	return func(L *lua.LState) int {
+		fn := L.CheckFunction(1)
+
+		beginner, ok := s.db.(Beginner)
+		if !ok {
+			L.RaiseError("transactions are not supported by this connection")
+			return 0
+		}
+
+		tx, err := beginner.Begin(s.ctx)
+		if err != nil {
+			L.RaiseError(err.Error())
+			return 0
+		}
+
+		outer := s.db
+		s.db = tx
+		top := L.GetTop()
+		err = L.CallByParam(lua.P{
+			Fn:      fn,
+			NRet:    lua.MultRet,
+			Protect: true,
+		})
+		s.db = outer
+
+		if err != nil {
+			rollbackErr := tx.Rollback(s.ctx)
+			if rollbackErr != nil {
+				L.RaiseError("%s; rolling back also failed: %s", err.Error(), rollbackErr.Error())
+				return 0
+			}
+
+			if apiErr, ok := err.(*lua.ApiError); ok {
+				L.Error(apiErr.Object, 0)
+				return 0
+			}
+
+			L.RaiseError(err.Error())
+			return 0
+		}
+
+		err = tx.Commit(s.ctx)
+		if err != nil {
+			L.RaiseError(err.Error())
+			return 0
+		}
+
+		return L.GetTop() - top
+	}
+}
+
 func raiseUnknownColumnTypeError(L *lua.LState, colIndex int, colName string, colValue any) int {
 	L.RaiseError("column %s (index %d) is of an unsupported type (%T); cast the value to a varchar or another type in your SQL query",
 		colName, colIndex, colValue)
@@ -0,0 +1,172 @@
+package luapgx
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+
+	lua "github.com/yuin/gopher-lua"
+)
+
+// null is the value of db.null, which encodes as NULL where a nil cannot be
+// used, such as in the middle of an array of parameters.
+type null struct{}
+
+func newNull(L *lua.LState) *lua.LUserData {
+	userData := L.NewUserData()
+	userData.Value = null{}
+	return userData
+}
+
+// encodeParam converts a Lua value to a Go value pgx knows how to encode as a
+// parameter. Numbers become integers when they have no fraction so they can
+// be used for integer columns, arrays become Postgres arrays, and other
+// tables become JSON.
+func encodeParam(value lua.LValue) (any, error) {
+	switch value := value.(type) {
+	case *lua.LNilType:
+		return nil, nil
+	case lua.LBool:
+		return bool(value), nil
+	case lua.LString:
+		return string(value), nil
+	case lua.LNumber:
+		return encodeNumber(value), nil
+	case *lua.LUserData:
+		if _, ok := value.Value.(null); ok {
+			return nil, nil
+		}
+	case *lua.LTable:
+		if iso, ok := temporalISO(value); ok {
+			return iso, nil
+		}
+
+		if isArray(value) {
+			return encodeArray(value)
+		}
+
+		object, err := jsonValue(value)
+		if err != nil {
+			return nil, err
+		}
+
+		encoded, err := json.Marshal(object)
+		if err != nil {
+			return nil, err
+		}
+
+		return string(encoded), nil
+	}
+
+	return nil, fmt.Errorf("a %s cannot be used as a parameter", value.Type().String())
+}
+
+// encodeNumber returns an int64 for numbers without a fraction that fit in
+// one, and a float64 otherwise.
+func encodeNumber(number lua.LNumber) any {
+	f := float64(number)
+	if f == math.Trunc(f) && f >= math.MinInt64 && f < math.MaxInt64 {
+		return int64(f)
+	}
+
+	return f
+}
+
+// encodeArray encodes the elements of an array for a Postgres array, whose
+// element type is decided by the parameter's type.
+func encodeArray(table *lua.LTable) ([]any, error) {
+	elements := make([]any, 0, table.Len())
+	for i := 1; i <= table.Len(); i++ {
+		element := table.RawGetInt(i)
+		if element, ok := element.(*lua.LTable); ok && !isTemporal(element) {
+			return nil, fmt.Errorf("arrays cannot contain tables; element %d is a table", i)
+		}
+
+		encoded, err := encodeParam(element)
+		if err != nil {
+			return nil, err
+		}
+
+		elements = append(elements, encoded)
+	}
+
+	return elements, nil
+}
+
+// jsonValue converts a Lua value to a value encoding/json can encode.
+func jsonValue(value lua.LValue) (any, error) {
+	switch value := value.(type) {
+	case *lua.LNilType:
+		return nil, nil
+	case lua.LBool:
+		return bool(value), nil
+	case lua.LString:
+		return string(value), nil
+	case lua.LNumber:
+		return encodeNumber(value), nil
+	case *lua.LUserData:
+		if _, ok := value.Value.(null); ok {
+			return nil, nil
+		}
+	case *lua.LTable:
+		if iso, ok := temporalISO(value); ok {
+			return iso, nil
+		}
+
+		if isArray(value) {
+			array := make([]any, 0, value.Len())
+			for i := 1; i <= value.Len(); i++ {
+				element, err := jsonValue(value.RawGetInt(i))
+				if err != nil {
+					return nil, err
+				}
+
+				array = append(array, element)
+			}
+
+			return array, nil
+		}
+
+		object := make(map[string]any)
+		var err error
+		value.ForEach(func(key lua.LValue, field lua.LValue) {
+			if err != nil {
+				return
+			}
+
+			var name string
+			switch key := key.(type) {
+			case lua.LString:
+				name = string(key)
+			case lua.LNumber:
+				name = strconv.FormatFloat(float64(key), 'f', -1, 64)
+			default:
+				err = fmt.Errorf("a %s cannot be used as a JSON key", key.Type().String())
+				return
+			}
+
+			object[name], err = jsonValue(field)
+		})
+
+		return object, err
+	}
+
+	return nil, fmt.Errorf("a %s cannot be converted to JSON", value.Type().String())
+}
+
+// isArray reports whether a table only has values at the positions 1 to n.
+// Empty tables are arrays.
+func isArray(table *lua.LTable) bool {
+	count := 0
+	array := true
+	table.ForEach(func(key lua.LValue, _ lua.LValue) {
+		count++
+		number, ok := key.(lua.LNumber)
+		if !ok || float64(number) != math.Trunc(float64(number)) || number < 1 {
+			array = false
+		}
+	})
+
+	return array && count == table.Len()
+}
@@ -0,0 +1,60 @@
+package luapgx
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	lua "github.com/yuin/gopher-lua"
+)
+
+func TestEncodeParam(t *testing.T) {
+	L := lua.NewState()
+	defer L.Close()
+	L.SetGlobal("null", newNull(L))
+
+	for _, test := range []struct {
+		name        string
+		value       string
+		expected    any
+		expectedErr string
+	}{
+		{name: "nil", value: "nil", expected: nil},
+		{name: "db.null", value: "null", expected: nil},
+		{name: "boolean", value: "true", expected: true},
+		{name: "string", value: `"abc"`, expected: "abc"},
+		{name: "integer", value: "9007199254740993", expected: int64(9007199254740992)},
+		{name: "negative integer", value: "-42", expected: int64(-42)},
+		{name: "float", value: "1.5", expected: 1.5},
+		{name: "too large for an integer", value: "1e300", expected: 1e300},
+		{name: "array", value: `{1, "two", true}`, expected: []any{int64(1), "two", true}},
+		{name: "array with db.null", value: `{1, null, 3}`, expected: []any{int64(1), nil, int64(3)}},
+		{name: "empty table", value: "{}", expected: []any{}},
+		{name: "map", value: `{a = 1, b = {1.5, "c"}, [3] = false}`, expected: `{"3":false,"a":1,"b":[1.5,"c"]}`},
+		{name: "array with a gap", value: `{[1] = "a", [3] = "c"}`, expected: `{"1":"a","3":"c"}`},
+		{name: "nested array", value: "{{1}}", expectedErr: "cannot contain tables"},
+		{name: "function", value: "print", expectedErr: "a function cannot be used"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			err := L.DoString("value = " + test.value)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			actual, err := encodeParam(L.GetGlobal("value"))
+			if test.expectedErr != "" {
+				if err == nil || !strings.Contains(err.Error(), test.expectedErr) {
+					t.Fatalf("expected error containing %q; got %v", test.expectedErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(actual, test.expected) {
+				t.Fatalf("expected %#v; got %#v", test.expected, actual)
+			}
+		})
+	}
+}
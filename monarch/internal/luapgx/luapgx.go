@@ -6,11 +6,27 @@ import (
 	lua "github.com/yuin/gopher-lua"
 )
 
+// session is the state shared by the functions of a db table.
+type session struct {
+	ctx context.Context
+	// db is the transaction opened by the innermost db.transaction call, or
+	// the Querier the table was created with outside of one
+	db Querier
+}
+
 func NewDBTable(ctx context.Context, L *lua.LState, globalName string, db Querier) {
+	s := &session{ctx: ctx, db: db}
+
 	table := L.NewTable()
 	L.SetFuncs(table, map[string]lua.LGFunction{
-		"exec":  dbExec(ctx, db),
-		"query": dbQuery(ctx, db),
+		"exec":         dbExec(s),
+		"query":        dbQuery(s),
+		"query_one":    dbQueryOne(s),
+		"query_all":    dbQueryAll(s),
+		"scalar":       dbScalar(s),
+		"transaction":  dbTransaction(s),
+		"batch_update": dbBatchUpdate(s),
 	})
+	L.SetField(table, "null", newNull(L))
 	L.SetGlobal(globalName, table)
 }
@@ -0,0 +1,98 @@
+package luapgx
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/tinyprint/monarch/monarch/internal/sqlscan"
+	lua "github.com/yuin/gopher-lua"
+)
+
+// bindParams returns the SQL and arguments for a params table, which is
+// either an array of positional parameters for $1, $2 and so on, or a table
+// of named parameters for :name placeholders, which are rewritten to
+// positional ones.
+func bindParams(sql string, params *lua.LTable) (string, []any, error) {
+	var positions []int
+	var names []string
+	var invalidKey lua.LValue
+	params.ForEach(func(key lua.LValue, _ lua.LValue) {
+		switch key := key.(type) {
+		case lua.LNumber:
+			position := int(key)
+			if lua.LNumber(position) != key || position < 1 {
+				invalidKey = key
+				return
+			}
+			positions = append(positions, position)
+		case lua.LString:
+			names = append(names, string(key))
+		default:
+			invalidKey = key
+		}
+	})
+
+	if invalidKey != nil {
+		return "", nil, fmt.Errorf("params cannot have the key %s; use an array or a table of names", invalidKey.String())
+	}
+
+	if len(positions) > 0 && len(names) > 0 {
+		return "", nil, fmt.Errorf("params mixes positional and named parameters; use an array or a table of names, not both")
+	}
+
+	if len(names) > 0 {
+		return bindNamedParams(sql, params, names)
+	}
+
+	sort.Ints(positions)
+	args := make([]any, len(positions))
+	for i, position := range positions {
+		if position != i+1 {
+			return "", nil, fmt.Errorf("params has no value at position %d; positional parameters cannot have gaps; use db.null for NULL", i+1)
+		}
+
+		arg, err := encodeParam(params.RawGetInt(position))
+		if err != nil {
+			return "", nil, fmt.Errorf("parameter $%d: %w", position, err)
+		}
+
+		args[i] = arg
+	}
+
+	return sql, args, nil
+}
+
+func bindNamedParams(sql string, params *lua.LTable, given []string) (string, []any, error) {
+	sql, names := sqlscan.BindNamed(sql)
+
+	used := make(map[string]bool, len(names))
+	args := make([]any, len(names))
+	for i, name := range names {
+		value := params.RawGetString(name)
+		if value == lua.LNil {
+			return "", nil, fmt.Errorf("params has no value for :%s", name)
+		}
+
+		arg, err := encodeParam(value)
+		if err != nil {
+			return "", nil, fmt.Errorf("parameter :%s: %w", name, err)
+		}
+
+		used[name] = true
+		args[i] = arg
+	}
+
+	var unused []string
+	for _, name := range given {
+		if !used[name] {
+			unused = append(unused, name)
+		}
+	}
+	if len(unused) > 0 {
+		sort.Strings(unused)
+		return "", nil, fmt.Errorf("params has values for parameters the statement does not use: %s", strings.Join(unused, ", "))
+	}
+
+	return sql, args, nil
+}
@@ -0,0 +1,94 @@
+package luapgx
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	lua "github.com/yuin/gopher-lua"
+)
+
+func TestBindParams(t *testing.T) {
+	L := lua.NewState()
+	defer L.Close()
+
+	for _, test := range []struct {
+		name         string
+		sql          string
+		params       string
+		expectedSQL  string
+		expectedArgs []any
+		expectedErr  string
+	}{
+		{
+			name:         "positional parameters",
+			sql:          "SELECT $1, $2",
+			params:       `{"a", "b"}`,
+			expectedSQL:  "SELECT $1, $2",
+			expectedArgs: []any{"a", "b"},
+		},
+		{
+			name:         "named parameters",
+			sql:          "SELECT :b, :a, :b",
+			params:       `{a = "a", b = "b"}`,
+			expectedSQL:  "SELECT $1, $2, $1",
+			expectedArgs: []any{"b", "a"},
+		},
+		{
+			name:         "no parameters",
+			sql:          "SELECT 1",
+			params:       `{}`,
+			expectedSQL:  "SELECT 1",
+			expectedArgs: []any{},
+		},
+		{
+			name:        "mixed parameters",
+			sql:         "SELECT $1, :a",
+			params:      `{"a", a = "a"}`,
+			expectedErr: "mixes positional and named parameters",
+		},
+		{
+			name:        "gap in positional parameters",
+			sql:         "SELECT $1, $2, $3",
+			params:      `{[1] = "a", [3] = "c"}`,
+			expectedErr: "no value at position 2",
+		},
+		{
+			name:        "missing named parameter",
+			sql:         "SELECT :a, :b",
+			params:      `{a = "a"}`,
+			expectedErr: "no value for :b",
+		},
+		{
+			name:        "unused named parameter",
+			sql:         "SELECT :a",
+			params:      `{a = "a", b = "b"}`,
+			expectedErr: "does not use: b",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			err := L.DoString("params = " + test.params)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			sql, args, err := bindParams(test.sql, L.GetGlobal("params").(*lua.LTable))
+			if test.expectedErr != "" {
+				if err == nil || !strings.Contains(err.Error(), test.expectedErr) {
+					t.Fatalf("expected error containing %q; got %v", test.expectedErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if sql != test.expectedSQL {
+				t.Fatalf("expected %q; got %q", test.expectedSQL, sql)
+			}
+			if !reflect.DeepEqual(args, test.expectedArgs) {
+				t.Fatalf("expected args %v; got %v", test.expectedArgs, args)
+			}
+		})
+	}
+}
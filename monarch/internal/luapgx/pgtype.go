@@ -3,6 +3,7 @@ package luapgx
 import (
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/jackc/pgx/v5/pgtype"
 	lua "github.com/yuin/gopher-lua"
@@ -27,7 +28,7 @@ func isUnknownColumnTypeError(err error) bool {
 	return isUnknownColumnType
 }
 
-func pgxToLuaValue(dataTypeOID uint32, value any) (lua.LValue, error) {
+func pgxToLuaValue(L *lua.LState, dataTypeOID uint32, value any) (lua.LValue, error) {
 	var lVal lua.LValue
 
 	switch val := value.(type) {
@@ -48,46 +49,14 @@ func pgxToLuaValue(dataTypeOID uint32, value any) (lua.LValue, error) {
 		}
 	case []byte:
 		lVal = lua.LString(val)
-	//case time.Time:
-	//	timeTable := L.NewTable()
-	//
-	//	if val.Location() != time.UTC {
-	//		return nil, fmt.Errorf("lua does not support timezones; convert column to a string, date, time, or timestamp (without tz)")
-	//	}
-	//
-	//	L.SetField(timeTable, "year", lua.LNumber(val.Year()))
-	//	L.SetField(timeTable, "month", lua.LNumber(val.Month()))
-	//	L.SetField(timeTable, "day", lua.LNumber(val.Day()))
-	//	L.SetField(timeTable, "hour", lua.LNumber(val.Hour()))
-	//	L.SetField(timeTable, "min", lua.LNumber(val.Minute()))
-	//	L.SetField(timeTable, "sec", lua.LNumber(val.Second()))
-	//	L.SetField(timeTable, "isdst", lua.LBool(val.IsDST()))
-	//	L.SetField(timeTable, "yday", lua.LNumber(val.YearDay()))
-	//	// in Go, Sunday=0 ... Saturday=6; in Lua, Sunday=1 ... Saturday=7
-	//	L.SetField(timeTable, "wday", lua.LNumber(val.Weekday()+1))
-	//
-	//	if pgtype.DateOID == dataTypeOID {
-	//		L.SetField(timeTable, "sql", lua.LString(val.Format(time.DateOnly)))
-	//	} else {
-	//		L.SetField(timeTable, "sql", lua.LString(val.Format(time.RFC3339)))
-	//	}
-	//
-	//	lVal = timeTable
-	//case pgtype.Interval:
-	//	intervalTable := L.NewTable()
-	//
-	//	L.SetField(intervalTable, "months", lua.LNumber(val.Months))
-	//	L.SetField(intervalTable, "days", lua.LNumber(val.Days))
-	//	L.SetField(intervalTable, "microseconds", lua.LNumber(val.Microseconds))
-	//
-	//	lVal = intervalTable
-	//case pgtype.Time:
-	//	timeTable := L.NewTable()
-	//
-	//	fmt.Println(dataTypeOID)
-	//	L.SetField(timeTable, "microseconds", lua.LNumber(val.Microseconds))
-	//
-	//	lVal = timeTable
+	case time.Time:
+		lVal = newTimeTable(L, dataTypeOID, val)
+	case pgtype.Interval:
+		lVal = newIntervalTable(L, val)
+	case pgtype.Time:
+		lVal = newTimeOfDayTable(L, val)
+	case pgtype.InfinityModifier: // infinite dates and timestamps
+		lVal = lua.LString(val.String())
 	case pgtype.Numeric:
 		asString, err := val.MarshalJSON()
 		if err != nil {
@@ -0,0 +1,82 @@
+package luapgx
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Statement is a statement ran through a Recorder with its bound parameters.
+type Statement struct {
+	SQL  string
+	Args []any
+}
+
+// Recorder is a Querier that records every statement before running it, so
+// the SQL a migration executes can be reviewed.
+type Recorder struct {
+	db         Querier
+	Statements []Statement
+}
+
+func NewRecorder(db Querier) *Recorder {
+	return &Recorder{db: db}
+}
+
+func (r *Recorder) Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error) {
+	r.record(sql, args)
+	return r.db.Exec(ctx, sql, args...)
+}
+
+func (r *Recorder) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+	r.record(sql, args)
+	return r.db.Query(ctx, sql, args...)
+}
+
+// Begin opens a transaction whose statements are recorded as well, so
+// db.transaction can be used while recording.
+func (r *Recorder) Begin(ctx context.Context) (pgx.Tx, error) {
+	beginner, ok := r.db.(Beginner)
+	if !ok {
+		return nil, errors.New("transactions are not supported by this connection")
+	}
+
+	tx, err := beginner.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &recordingTx{Tx: tx, recorder: r}, nil
+}
+
+func (r *Recorder) record(sql string, args []any) {
+	r.Statements = append(r.Statements, Statement{SQL: sql, Args: args})
+}
+
+// recordingTx is a transaction opened through a Recorder.
+type recordingTx struct {
+	pgx.Tx
+	recorder *Recorder
+}
+
+// Begin opens a savepoint whose statements are recorded by the same Recorder.
+func (tx *recordingTx) Begin(ctx context.Context) (pgx.Tx, error) {
+	savepoint, err := tx.Tx.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &recordingTx{Tx: savepoint, recorder: tx.recorder}, nil
+}
+
+func (tx *recordingTx) Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error) {
+	tx.recorder.record(sql, args)
+	return tx.Tx.Exec(ctx, sql, args...)
+}
+
+func (tx *recordingTx) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+	tx.recorder.record(sql, args)
+	return tx.Tx.Query(ctx, sql, args...)
+}
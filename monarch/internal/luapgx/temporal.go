@@ -0,0 +1,146 @@
+package luapgx
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	lua "github.com/yuin/gopher-lua"
+)
+
+// temporalTypeName names the metatable of the tables dates, times, timestamps
+// and intervals are returned as, which lets them be used as parameters again.
+const temporalTypeName = "luapgx.temporal"
+
+const microsecondsPerSecond = int64(time.Second / time.Microsecond)
+
+// newTimeTable returns a date or timestamp as a table with the fields of
+// os.date("*t") and an ISO 8601 iso field. Timestamps with time zones are
+// returned in UTC so the fields do not depend on the time zone of the host.
+func newTimeTable(L *lua.LState, dataTypeOID uint32, t time.Time) *lua.LTable {
+	var iso string
+	switch dataTypeOID {
+	case pgtype.DateOID:
+		iso = t.Format(time.DateOnly)
+	case pgtype.TimestamptzOID:
+		t = t.UTC()
+		iso = t.Format("2006-01-02T15:04:05.999999Z07:00")
+	default:
+		iso = t.Format("2006-01-02T15:04:05.999999")
+	}
+
+	table := newTemporalTable(L, iso)
+	L.SetField(table, "year", lua.LNumber(t.Year()))
+	L.SetField(table, "month", lua.LNumber(t.Month()))
+	L.SetField(table, "day", lua.LNumber(t.Day()))
+	L.SetField(table, "hour", lua.LNumber(t.Hour()))
+	L.SetField(table, "min", lua.LNumber(t.Minute()))
+	L.SetField(table, "sec", lua.LNumber(t.Second()))
+	// in Go, Sunday=0 ... Saturday=6; in Lua, Sunday=1 ... Saturday=7
+	L.SetField(table, "wday", lua.LNumber(t.Weekday()+1))
+	L.SetField(table, "yday", lua.LNumber(t.YearDay()))
+	L.SetField(table, "isdst", lua.LFalse)
+
+	return table
+}
+
+// newTimeOfDayTable returns a time as a table with hour, min and sec fields
+// and an ISO 8601 iso field.
+func newTimeOfDayTable(L *lua.LState, t pgtype.Time) *lua.LTable {
+	seconds := t.Microseconds / microsecondsPerSecond
+	hour, minute, second := seconds/3600, seconds/60%60, seconds%60
+
+	iso := fmt.Sprintf("%02d:%02d:%02d", hour, minute, second)
+	if fraction := t.Microseconds % microsecondsPerSecond; fraction != 0 {
+		iso += strings.TrimRight(fmt.Sprintf(".%06d", fraction), "0")
+	}
+
+	table := newTemporalTable(L, iso)
+	L.SetField(table, "hour", lua.LNumber(hour))
+	L.SetField(table, "min", lua.LNumber(minute))
+	L.SetField(table, "sec", lua.LNumber(second))
+
+	return table
+}
+
+// newIntervalTable returns an interval as a table of the months, days and
+// microseconds Postgres stores it as, which cannot be converted to each
+// other, and an ISO 8601 iso field.
+func newIntervalTable(L *lua.LState, interval pgtype.Interval) *lua.LTable {
+	table := newTemporalTable(L, intervalISO(interval))
+	L.SetField(table, "months", lua.LNumber(interval.Months))
+	L.SetField(table, "days", lua.LNumber(interval.Days))
+	L.SetField(table, "microseconds", lua.LNumber(interval.Microseconds))
+
+	return table
+}
+
+// intervalISO formats an interval as an ISO 8601 duration, such as
+// P1Y2M3DT4H5M6S, which Postgres accepts as interval input.
+func intervalISO(interval pgtype.Interval) string {
+	var date, clock strings.Builder
+
+	part := func(b *strings.Builder, value int64, designator string) {
+		if value != 0 {
+			b.WriteString(strconv.FormatInt(value, 10) + designator)
+		}
+	}
+
+	part(&date, int64(interval.Months/12), "Y")
+	part(&date, int64(interval.Months%12), "M")
+	part(&date, int64(interval.Days), "D")
+
+	microseconds := interval.Microseconds
+	part(&clock, microseconds/(3600*microsecondsPerSecond), "H")
+	part(&clock, microseconds/(60*microsecondsPerSecond)%60, "M")
+	if seconds := microseconds % (60 * microsecondsPerSecond); seconds != 0 {
+		formatted := strconv.FormatFloat(float64(seconds)/float64(microsecondsPerSecond), 'f', -1, 64)
+		clock.WriteString(formatted + "S")
+	}
+
+	if date.Len() == 0 && clock.Len() == 0 {
+		return "PT0S"
+	}
+
+	if clock.Len() == 0 {
+		return "P" + date.String()
+	}
+
+	return "P" + date.String() + "T" + clock.String()
+}
+
+func newTemporalTable(L *lua.LState, iso string) *lua.LTable {
+	metatable := L.NewTypeMetatable(temporalTypeName)
+	if metatable.RawGetString("__name") == lua.LNil {
+		L.SetField(metatable, "__name", lua.LString(temporalTypeName))
+		L.SetField(metatable, "__tostring", L.NewFunction(func(L *lua.LState) int {
+			L.Push(L.CheckTable(1).RawGetString("iso"))
+			return 1
+		}))
+	}
+
+	table := L.NewTable()
+	L.SetField(table, "iso", lua.LString(iso))
+	L.SetMetatable(table, metatable)
+
+	return table
+}
+
+// temporalISO returns the iso field of a table returned by newTimeTable,
+// newTimeOfDayTable or newIntervalTable.
+func temporalISO(table *lua.LTable) (string, bool) {
+	metatable, ok := table.Metatable.(*lua.LTable)
+	if !ok || metatable.RawGetString("__name") != lua.LString(temporalTypeName) {
+		return "", false
+	}
+
+	iso, ok := table.RawGetString("iso").(lua.LString)
+	return string(iso), ok
+}
+
+func isTemporal(table *lua.LTable) bool {
+	_, ok := temporalISO(table)
+	return ok
+}
@@ -0,0 +1,32 @@
+package luapgx
+
+import (
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func TestIntervalISO(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		interval pgtype.Interval
+		expected string
+	}{
+		{name: "zero", interval: pgtype.Interval{}, expected: "PT0S"},
+		{name: "date parts", interval: pgtype.Interval{Months: 63, Days: 12}, expected: "P5Y3M12D"},
+		{name: "time parts", interval: pgtype.Interval{Microseconds: 3723_500000}, expected: "PT1H2M3.5S"},
+		{
+			name:     "date and time parts",
+			interval: pgtype.Interval{Months: 63, Days: 12, Microseconds: 3 * 3600_000000},
+			expected: "P5Y3M12DT3H",
+		},
+		{name: "negative parts", interval: pgtype.Interval{Months: -14, Microseconds: -90_000000}, expected: "P-1Y-2MT-1M-30S"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			actual := intervalISO(test.interval)
+			if actual != test.expected {
+				t.Fatalf("expected %q; got %q", test.expected, actual)
+			}
+		})
+	}
+}
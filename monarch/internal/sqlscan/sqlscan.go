@@ -0,0 +1,209 @@
+// Package sqlscan splits Postgres SQL into lexemes well enough to find the
+// statement separators and placeholders outside of strings, quoted
+// identifiers and comments.
+package sqlscan
+
+import (
+	"strconv"
+	"strings"
+)
+
+type lexemeKind int
+
+const (
+	lexemeCode lexemeKind = iota
+	lexemeString
+	lexemeQuotedIdentifier
+	lexemeDollarQuoted
+	lexemeComment
+)
+
+// Split splits a script into its statements, dropping the semicolons between
+// them and any statements that only contain whitespace and comments.
+func Split(script string) []string {
+	var statements []string
+
+	start, hasCode := 0, false
+	for i := 0; i < len(script); {
+		end, kind := nextLexeme(script, i)
+
+		switch {
+		case kind == lexemeCode && script[i] == ';':
+			if hasCode {
+				statements = append(statements, strings.TrimSpace(script[start:i]))
+			}
+			start, hasCode = end, false
+		case kind != lexemeComment && !isSpace(script[i]):
+			hasCode = true
+		}
+
+		i = end
+	}
+
+	if hasCode {
+		statements = append(statements, strings.TrimSpace(script[start:]))
+	}
+
+	return statements
+}
+
+// BindNamed replaces named parameters like :name outside of strings, quoted
+// identifiers and comments with positional parameters like $1, and returns
+// the names in the order of their positions. A name used more than once gets
+// the same position each time. Type casts like ::int are left alone.
+func BindNamed(sql string) (string, []string) {
+	var bound strings.Builder
+	var names []string
+	positions := make(map[string]int)
+
+	for i := 0; i < len(sql); {
+		end, kind := nextLexeme(sql, i)
+
+		if kind == lexemeCode && sql[i] == ':' {
+			switch {
+			case i+1 < len(sql) && sql[i+1] == ':':
+				end = i + 2
+			case i+1 < len(sql) && isNameStart(sql[i+1]):
+				end = i + 1
+				for end < len(sql) && isIdentifierChar(sql[end]) {
+					end++
+				}
+
+				name := sql[i+1 : end]
+				position, ok := positions[name]
+				if !ok {
+					names = append(names, name)
+					position = len(names)
+					positions[name] = position
+				}
+
+				bound.WriteString("$" + strconv.Itoa(position))
+				i = end
+				continue
+			}
+		}
+
+		bound.WriteString(sql[i:end])
+		i = end
+	}
+
+	return bound.String(), names
+}
+
+// nextLexeme returns the end of the lexeme starting at i. Code is returned
+// a byte at a time; everything else is returned whole, or up to the end of
+// the script when it is unterminated.
+func nextLexeme(s string, i int) (int, lexemeKind) {
+	switch {
+	case strings.HasPrefix(s[i:], "--"):
+		end := strings.IndexByte(s[i:], '\n')
+		if end == -1 {
+			return len(s), lexemeComment
+		}
+		return i + end + 1, lexemeComment
+	case strings.HasPrefix(s[i:], "/*"):
+		return blockCommentEnd(s, i), lexemeComment
+	case s[i] == '\'':
+		escapes := i > 0 && (s[i-1] == 'E' || s[i-1] == 'e') && (i == 1 || !isIdentifierChar(s[i-2]))
+		return quotedEnd(s, i, '\'', escapes), lexemeString
+	case s[i] == '"':
+		return quotedEnd(s, i, '"', false), lexemeQuotedIdentifier
+	case s[i] == '$' && (i == 0 || !isIdentifierChar(s[i-1])):
+		if tag, ok := dollarQuoteTag(s, i); ok {
+			end := strings.Index(s[i+len(tag):], tag)
+			if end == -1 {
+				return len(s), lexemeDollarQuoted
+			}
+			return i + len(tag) + end + len(tag), lexemeDollarQuoted
+		}
+	}
+
+	if isIdentifierChar(s[i]) {
+		// identifiers are returned whole so a quote or dollar sign inside of
+		// one, like in escape strings or tag$name, is not mistaken for a new
+		// lexeme
+		end := i
+		for end < len(s) && (isIdentifierChar(s[end]) || s[end] == '$') {
+			end++
+		}
+		return end, lexemeCode
+	}
+
+	return i + 1, lexemeCode
+}
+
+// blockCommentEnd returns the end of a block comment, which can be nested.
+func blockCommentEnd(s string, i int) int {
+	depth := 0
+	for i < len(s) {
+		switch {
+		case strings.HasPrefix(s[i:], "/*"):
+			depth++
+			i += 2
+		case strings.HasPrefix(s[i:], "*/"):
+			depth--
+			i += 2
+			if depth == 0 {
+				return i
+			}
+		default:
+			i++
+		}
+	}
+
+	return len(s)
+}
+
+// quotedEnd returns the end of a quoted string, where the quote is escaped by
+// doubling it and, in escape strings like E'\n', with a backslash.
+func quotedEnd(s string, i int, quote byte, backslashEscapes bool) int {
+	for i++; i < len(s); i++ {
+		switch {
+		case backslashEscapes && s[i] == '\\':
+			i++
+		case s[i] == quote && i+1 < len(s) && s[i+1] == quote:
+			i++
+		case s[i] == quote:
+			return i + 1
+		}
+	}
+
+	return len(s)
+}
+
+// dollarQuoteTag returns the $tag$ starting at i, which is not a dollar quote
+// when it is a positional parameter like $1.
+func dollarQuoteTag(s string, i int) (string, bool) {
+	for end := i + 1; end < len(s); end++ {
+		c := s[end]
+		switch {
+		case c == '$':
+			return s[i : end+1], true
+		case c >= '0' && c <= '9' && end == i+1:
+			return "", false
+		case !isIdentifierChar(c):
+			return "", false
+		}
+	}
+
+	return "", false
+}
+
+func isIdentifierChar(c byte) bool {
+	return c == '_' ||
+		(c >= 'a' && c <= 'z') ||
+		(c >= 'A' && c <= 'Z') ||
+		(c >= '0' && c <= '9') ||
+		c >= 0x80
+}
+
+// isNameStart reports whether c can start a named parameter, which unlike
+// other identifiers cannot start with a digit so array slices like a[1:2]
+// are left alone.
+func isNameStart(c byte) bool {
+	return isIdentifierChar(c) && (c < '0' || c > '9')
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f' || c == '\v'
+}
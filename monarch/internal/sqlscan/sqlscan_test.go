@@ -0,0 +1,113 @@
+package sqlscan
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplit(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		script   string
+		expected []string
+	}{
+		{
+			name:     "single statement without semicolon",
+			script:   "SELECT 1",
+			expected: []string{"SELECT 1"},
+		},
+		{
+			name:     "multiple statements",
+			script:   "SELECT 1;\nSELECT 2;\n",
+			expected: []string{"SELECT 1", "SELECT 2"},
+		},
+		{
+			name:     "empty statements and trailing comments",
+			script:   ";;SELECT 1;\n-- the end\n/* really */",
+			expected: []string{"SELECT 1"},
+		},
+		{
+			name:     "semicolons in strings and identifiers",
+			script:   `SELECT 'a;b', "c;d", E'e\';f', 'g'';h'; SELECT 2`,
+			expected: []string{`SELECT 'a;b', "c;d", E'e\';f', 'g'';h'`, "SELECT 2"},
+		},
+		{
+			name:     "semicolons in comments",
+			script:   "SELECT 1 -- one; two\n; /* three; /* four; */ five; */ SELECT 2",
+			expected: []string{"SELECT 1 -- one; two", "/* three; /* four; */ five; */ SELECT 2"},
+		},
+		{
+			name: "dollar quoted function bodies",
+			script: `CREATE FUNCTION f() RETURNS int AS $$
+	BEGIN
+		RETURN 1;
+	END;
+$$ LANGUAGE plpgsql;
+CREATE FUNCTION g() RETURNS text AS $body$ SELECT '$$;'; $body$ LANGUAGE sql;`,
+			expected: []string{
+				"CREATE FUNCTION f() RETURNS int AS $$\n\tBEGIN\n\t\tRETURN 1;\n\tEND;\n$$ LANGUAGE plpgsql",
+				"CREATE FUNCTION g() RETURNS text AS $body$ SELECT '$$;'; $body$ LANGUAGE sql",
+			},
+		},
+		{
+			name:     "positional parameters are not dollar quotes",
+			script:   "SELECT $1; SELECT $2",
+			expected: []string{"SELECT $1", "SELECT $2"},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			actual := Split(test.script)
+			if !reflect.DeepEqual(actual, test.expected) {
+				t.Fatalf("expected %q; got %q", test.expected, actual)
+			}
+		})
+	}
+}
+
+func TestBindNamed(t *testing.T) {
+	for _, test := range []struct {
+		name          string
+		sql           string
+		expectedSQL   string
+		expectedNames []string
+	}{
+		{
+			name:        "no named parameters",
+			sql:         "SELECT $1",
+			expectedSQL: "SELECT $1",
+		},
+		{
+			name:          "named parameters in order of first use",
+			sql:           "SELECT * FROM users WHERE id = :id AND name = :name OR id = :id",
+			expectedSQL:   "SELECT * FROM users WHERE id = $1 AND name = $2 OR id = $1",
+			expectedNames: []string{"id", "name"},
+		},
+		{
+			name:          "casts are not named parameters",
+			sql:           "SELECT :value::int, '1'::text",
+			expectedSQL:   "SELECT $1::int, '1'::text",
+			expectedNames: []string{"value"},
+		},
+		{
+			name:          "strings, identifiers and comments are left alone",
+			sql:           "SELECT ':a', \":b\", $$:c$$ -- :d\n/* :e */, :f",
+			expectedSQL:   "SELECT ':a', \":b\", $$:c$$ -- :d\n/* :e */, $1",
+			expectedNames: []string{"f"},
+		},
+		{
+			name:        "array slices are left alone",
+			sql:         "SELECT a[1:2] FROM t",
+			expectedSQL: "SELECT a[1:2] FROM t",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			actualSQL, actualNames := BindNamed(test.sql)
+			if actualSQL != test.expectedSQL {
+				t.Fatalf("expected %q; got %q", test.expectedSQL, actualSQL)
+			}
+			if !reflect.DeepEqual(actualNames, test.expectedNames) {
+				t.Fatalf("expected names %q; got %q", test.expectedNames, actualNames)
+			}
+		})
+	}
+}
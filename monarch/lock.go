@@ -0,0 +1,132 @@
+package monarch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	// defaultLockKey is "monarch" in ASCII, so it is unlikely to collide with
+	// advisory locks taken by applications.
+	defaultLockKey int64 = 0x6d6f6e61726368
+	// DefaultLockTimeout is how long a run waits for another run to release
+	// the advisory lock before giving up.
+	DefaultLockTimeout = 5 * time.Minute
+
+	lockPollInterval = time.Second
+)
+
+// WithLockKey sets the key of the Postgres advisory lock held while
+// migrations are changed, so separate sets of migrations in the same
+// database can run at the same time.
+func WithLockKey(key int64) Option {
+	return func(m *Migrator) {
+		m.lockKey = key
+	}
+}
+
+// WithLockTimeout sets how long to wait for the advisory lock when another
+// run holds it; a timeout of zero waits indefinitely.
+func WithLockTimeout(timeout time.Duration) Option {
+	return func(m *Migrator) {
+		m.lockTimeout = timeout
+	}
+}
+
+// withLock calls fn while holding the advisory lock so concurrent runs, such
+// as several pods migrating on deploy, cannot apply the same migration twice.
+func (m *Migrator) withLock(ctx context.Context, fn func() error) error {
+	return m.withConn(ctx, func() (err error) {
+		err = m.lock(ctx)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			_, unlockErr := m.conn.Exec(ctx, "SELECT pg_advisory_unlock($1)", m.lockKey)
+			err = errors.Join(err, unlockErr)
+		}()
+
+		// other runs may have migrated since the cache was loaded, so what is
+		// pending is decided again while holding the lock
+		m.model.reset()
+
+		return fn()
+	})
+}
+
+func (m *Migrator) lock(ctx context.Context) error {
+	var deadline time.Time
+	if m.lockTimeout > 0 {
+		deadline = time.Now().Add(m.lockTimeout)
+	}
+
+	var holder int32
+	for {
+		var locked bool
+		err := m.conn.QueryRow(ctx, "SELECT pg_try_advisory_lock($1)", m.lockKey).Scan(&locked)
+		if err != nil {
+			return err
+		}
+		if locked {
+			return nil
+		}
+
+		pid, err := m.lockHolder(ctx)
+		if err != nil {
+			return err
+		}
+		if pid != holder && pid != 0 {
+			holder = pid
+			fmt.Printf("waiting for lock held by pid %d\n", holder)
+		}
+
+		if !deadline.IsZero() && time.Now().After(deadline) {
+			return fmt.Errorf(
+				"timed out after %s waiting for lock held by pid %d",
+				m.lockTimeout,
+				holder,
+			)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(lockPollInterval):
+		}
+	}
+}
+
+// lockHolder returns the pid of the backend holding the advisory lock, or 0
+// when the lock was released since it was last tried.
+func (m *Migrator) lockHolder(ctx context.Context) (int32, error) {
+	rows, err := m.conn.Query(
+		ctx,
+		`
+			SELECT pid
+			FROM pg_locks
+			WHERE locktype = 'advisory'
+				AND database = (SELECT oid FROM pg_database WHERE datname = current_database())
+				AND granted
+				AND objsubid = 1
+				AND classid::bigint = ($1::bigint >> 32)
+				AND objid::bigint = ($1::bigint & 4294967295)
+		`,
+		m.lockKey,
+	)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	var pid int32
+	if rows.Next() {
+		err = rows.Scan(&pid)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return pid, rows.Err()
+}
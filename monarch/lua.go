@@ -2,6 +2,10 @@ package monarch
 
 import (
 	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
 
 	lua "github.com/yuin/gopher-lua"
 
@@ -9,7 +13,10 @@ import (
 )
 
 type runLuaConfig struct {
-	file string
+	// file is opened from fsys, or from the local filesystem when fsys is nil
+	file     string
+	fsys     fs.FS
+	rollback bool
 }
 
 func luaEnv() (*lua.LState, error) {
@@ -44,11 +51,77 @@ func runLua(ctx context.Context, db luapgx.Querier, config runLuaConfig) error {
 		return err
 	}
 
-	luapgx.NewDBTable(ctx, L, "db", db)
+	defer L.Close()
 
-	if err := L.DoFile(config.file); err != nil {
+	if config.rollback {
+		// migrations without up and down functions do their work at the top
+		// level of the file, so db is withheld until down is called to
+		// prevent rolling back from running the migration again
+		withholdDBTable(L, "db")
+	} else {
+		luapgx.NewDBTable(ctx, L, "db", db)
+	}
+
+	if err := doFile(L, config.fsys, config.file); err != nil {
+		return err
+	}
+
+	fnName := "up"
+	if config.rollback {
+		fnName = "down"
+	}
+
+	fn := L.GetGlobal(fnName)
+	if fn.Type() != lua.LTFunction {
+		if config.rollback {
+			return errors.New("migration does not define a down function")
+		}
+
+		// migrations without an up function ran at the top level of the file
+		return nil
+	}
+
+	if config.rollback {
+		luapgx.NewDBTable(ctx, L, "db", db)
+	}
+
+	return L.CallByParam(lua.P{
+		Fn:      fn,
+		NRet:    0,
+		Protect: true,
+	})
+}
+
+func doFile(L *lua.LState, fsys fs.FS, file string) error {
+	var source io.ReadCloser
+	var err error
+	if fsys == nil {
+		source, err = os.Open(file)
+	} else {
+		source, err = fsys.Open(file)
+	}
+	if err != nil {
+		return err
+	}
+	defer source.Close()
+
+	fn, err := L.Load(source, file)
+	if err != nil {
 		return err
 	}
 
-	return nil
+	L.Push(fn)
+
+	return L.PCall(0, lua.MultRet, nil)
+}
+
+func withholdDBTable(L *lua.LState, globalName string) {
+	table := L.NewTable()
+	meta := L.NewTable()
+	L.SetField(meta, "__index", L.NewFunction(func(L *lua.LState) int {
+		L.RaiseError("%s can only be used inside the down function when rolling back; migrations without a down function cannot be rolled back", globalName)
+		return 0
+	}))
+	L.SetMetatable(table, meta)
+	L.SetGlobal(globalName, table)
 }
@@ -75,3 +75,108 @@ func TestUnfinishedQueryIteratorCanBeManuallyClosed(t *testing.T) {
 		t.Fatalf("Lua test file failed with errors: %s", err)
 	}
 }
+
+func TestTransactionRollsBackOnError(t *testing.T) {
+	databaseURL := os.Getenv("DATABASE_URL")
+	ctx := context.Background()
+
+	if databaseURL == "" {
+		t.Fatal("provide a database URL via DATABASE_URL env var")
+	}
+
+	db, err := pgx.Connect(context.Background(), databaseURL)
+	if err != nil {
+		t.Fatalf("connection to database failed: %s", err)
+	}
+
+	err = runLua(ctx, db, runLuaConfig{
+		file: "./test/lua_transaction_rolls_back_on_error.lua",
+	})
+	if err != nil {
+		t.Fatalf("Lua test file failed with errors: %s", err)
+	}
+}
+
+func TestQueryHelpersDrainRows(t *testing.T) {
+	databaseURL := os.Getenv("DATABASE_URL")
+	ctx := context.Background()
+
+	if databaseURL == "" {
+		t.Fatal("provide a database URL via DATABASE_URL env var")
+	}
+
+	db, err := pgx.Connect(context.Background(), databaseURL)
+	if err != nil {
+		t.Fatalf("connection to database failed: %s", err)
+	}
+
+	err = runLua(ctx, db, runLuaConfig{
+		file: "./test/lua_query_helpers_drain_rows.lua",
+	})
+	if err != nil {
+		t.Fatalf("Lua test file failed with errors: %s", err)
+	}
+}
+
+func TestExecReturnsRowsAffected(t *testing.T) {
+	databaseURL := os.Getenv("DATABASE_URL")
+	ctx := context.Background()
+
+	if databaseURL == "" {
+		t.Fatal("provide a database URL via DATABASE_URL env var")
+	}
+
+	db, err := pgx.Connect(context.Background(), databaseURL)
+	if err != nil {
+		t.Fatalf("connection to database failed: %s", err)
+	}
+
+	err = runLua(ctx, db, runLuaConfig{
+		file: "./test/lua_exec_returns_rows_affected.lua",
+	})
+	if err != nil {
+		t.Fatalf("Lua test file failed with errors: %s", err)
+	}
+}
+
+func TestBatchUpdateRunsUntilNoRowsAreAffected(t *testing.T) {
+	databaseURL := os.Getenv("DATABASE_URL")
+	ctx := context.Background()
+
+	if databaseURL == "" {
+		t.Fatal("provide a database URL via DATABASE_URL env var")
+	}
+
+	db, err := pgx.Connect(context.Background(), databaseURL)
+	if err != nil {
+		t.Fatalf("connection to database failed: %s", err)
+	}
+
+	err = runLua(ctx, db, runLuaConfig{
+		file: "./test/lua_batch_update_runs_until_no_rows_are_affected.lua",
+	})
+	if err != nil {
+		t.Fatalf("Lua test file failed with errors: %s", err)
+	}
+}
+
+func TestParamsEncodeAsExpected(t *testing.T) {
+	databaseURL := os.Getenv("DATABASE_URL")
+	ctx := context.Background()
+
+	if databaseURL == "" {
+		t.Fatal("provide a database URL via DATABASE_URL env var")
+	}
+
+	db, err := pgx.Connect(context.Background(), databaseURL)
+	if err != nil {
+		t.Fatalf("connection to database failed: %s", err)
+	}
+
+	err = runLua(ctx, db, runLuaConfig{
+		file: "./test/lua_params_encode_as_expected.lua",
+	})
+	if err != nil {
+		t.Fatalf("Lua test file failed with errors: %s", err)
+	}
+}
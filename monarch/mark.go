@@ -0,0 +1,126 @@
+package monarch
+
+import (
+	"context"
+	"fmt"
+	"os/user"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	auditMarkApplied = "mark-applied"
+	auditMarkPending = "mark-pending"
+)
+
+// MarkApplied records a migration as migrated without running it, such as
+// after a hotfix was applied by hand. The change is recorded in the audit
+// table along with who made it.
+func (m *Migrator) MarkApplied(ctx context.Context, name string) error {
+	return m.withLock(ctx, func() error {
+		return m.markApplied(ctx, name)
+	})
+}
+
+// MarkPending removes a migration from the migrations table without rolling
+// it back, so it runs again on the next migrate. The change is recorded in
+// the audit table along with who made it.
+func (m *Migrator) MarkPending(ctx context.Context, name string) error {
+	return m.withLock(ctx, func() error {
+		return m.markPending(ctx, name)
+	})
+}
+
+func (m *Migrator) markApplied(ctx context.Context, name string) error {
+	migrationName, err := m.files.resolveName(name)
+	if err != nil {
+		return err
+	}
+
+	checksum, err := m.files.checksum(migrationName)
+	if err != nil {
+		return fmt.Errorf("migration file %s does not exist", migrationName)
+	}
+
+	err = m.upgradeBookkeeping(ctx)
+	if err != nil {
+		return err
+	}
+
+	isMigrated, err := m.model.IsMigrated(ctx, migrationName)
+	if err != nil {
+		return err
+	}
+	if isMigrated {
+		return fmt.Errorf("%s is already migrated", migrationName)
+	}
+
+	run := m.newRecord(AppliedViaManual, checksum)
+	err = m.inTransaction(ctx, func(tx pgx.Tx) error {
+		model := m.modelFor(tx)
+		err := model.MarkAsMigrated(ctx, migrationName, run)
+		if err != nil {
+			return err
+		}
+
+		return model.Audit(ctx, migrationName, auditMarkApplied, osUsername(), run)
+	})
+	if err != nil {
+		return err
+	}
+
+	m.model.remember(migrationName)
+	fmt.Printf("marked %s as applied\n", migrationName)
+
+	return nil
+}
+
+func (m *Migrator) markPending(ctx context.Context, name string) error {
+	migrationName, err := m.files.resolveName(name)
+	if err != nil {
+		return err
+	}
+
+	err = m.upgradeBookkeeping(ctx)
+	if err != nil {
+		return err
+	}
+
+	isMigrated, err := m.model.IsMigrated(ctx, migrationName)
+	if err != nil {
+		return err
+	}
+	if !isMigrated {
+		return fmt.Errorf("%s is not migrated", migrationName)
+	}
+
+	run := m.newRecord("", "")
+	err = m.inTransaction(ctx, func(tx pgx.Tx) error {
+		model := m.modelFor(tx)
+		err := model.MarkAsRolledBack(ctx, migrationName)
+		if err != nil {
+			return err
+		}
+
+		return model.Audit(ctx, migrationName, auditMarkPending, osUsername(), run)
+	})
+	if err != nil {
+		return err
+	}
+
+	m.model.forget(migrationName)
+	fmt.Printf("marked %s as pending\n", migrationName)
+
+	return nil
+}
+
+// osUsername returns the name of the user running monarch, or an empty
+// string when it cannot be looked up, such as in a scratch container.
+func osUsername() string {
+	current, err := user.Current()
+	if err != nil {
+		return ""
+	}
+
+	return current.Username
+}
@@ -0,0 +1,75 @@
+package monarch
+
+import (
+	"os"
+	"runtime/debug"
+	"time"
+)
+
+const modulePath = "github.com/tinyprint/monarch"
+
+// AppliedVia is how a migration came to be recorded as migrated.
+type AppliedVia string
+
+const (
+	// AppliedViaMigrate is a migration that was run by monarch.
+	AppliedViaMigrate AppliedVia = "migrate"
+	// AppliedViaBaseline is a migration that was recorded as migrated by
+	// Baseline without running it.
+	AppliedViaBaseline AppliedVia = "baseline"
+	// AppliedViaManual is a migration that was recorded as migrated by
+	// MarkApplied, usually after it was applied by hand.
+	AppliedViaManual AppliedVia = "manual"
+)
+
+// WithDeployID records an identifier, such as a CI build number or a release
+// tag, with every migration migrated or reapplied.
+func WithDeployID(deployID string) Option {
+	return func(m *Migrator) {
+		m.deployID = deployID
+	}
+}
+
+// Version returns the version of monarch built into the running binary, or
+// "(devel)" when it is not built from a tagged module.
+func Version() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "(devel)"
+	}
+
+	if info.Main.Path == modulePath {
+		return info.Main.Version
+	}
+
+	for _, dep := range info.Deps {
+		if dep.Path == modulePath {
+			return dep.Version
+		}
+	}
+
+	return "(devel)"
+}
+
+// newRun describes a migration run that started at started to record it.
+func (m *Migrator) newRun(checksum string, started time.Time) migrationRun {
+	run := m.newRecord(AppliedViaMigrate, checksum)
+	run.duration = time.Since(started)
+
+	return run
+}
+
+// newRecord describes a migration that is recorded without running it.
+func (m *Migrator) newRecord(appliedVia AppliedVia, checksum string) migrationRun {
+	// the hostname is informational, so a failure to look it up is recorded
+	// as an empty hostname instead of failing the migration
+	hostname, _ := os.Hostname()
+
+	return migrationRun{
+		appliedVia:     appliedVia,
+		checksum:       checksum,
+		hostname:       hostname,
+		monarchVersion: Version(),
+		deployID:       m.deployID,
+	}
+}
@@ -2,12 +2,18 @@ package monarch
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io/fs"
 	"path"
 	"regexp"
-	"strings"
+	"time"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/tinyprint/monarch/monarch/internal/luapgx"
 )
 
 var regexpMigValidateName = regexp.MustCompile("^[a-zA-Z0-9_]+$")
@@ -16,18 +22,80 @@ type (
 	MigrationFunc func(ctx context.Context, db *pgx.Conn, rollback bool) (bool, error)
 )
 
+type TransactionMode int
+
+const (
+	// TransactionPerMigration runs each migration in its own transaction that
+	// is committed as soon as the migration succeeds.
+	TransactionPerMigration TransactionMode = iota
+	// TransactionPerBatch runs every pending migration in a single transaction
+	// so either all of them are migrated or none of them are.
+	TransactionPerBatch
+)
+
+type Option func(*Migrator)
+
+// WithTransactionMode sets how migrations are grouped into transactions; the
+// default is TransactionPerMigration.
+func WithTransactionMode(mode TransactionMode) Option {
+	return func(m *Migrator) {
+		m.transactionMode = mode
+	}
+}
+
+// DB is a connection migrations can run with, such as a *pgx.Conn, a
+// *pgxpool.Conn or a *pgxpool.Pool.
+type DB interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+	Begin(ctx context.Context) (pgx.Tx, error)
+}
+
+// Migrator runs migrations; it is not safe for concurrent use.
 type Migrator struct {
-	db    *pgx.Conn
-	model *model
-	files *files
+	db DB
+	// conn is db, or a connection acquired from db for the current run when
+	// db is a pool; see withConn
+	conn             DB
+	model            *model
+	files            *files
+	transactionMode  TransactionMode
+	lockKey          int64
+	lockTimeout      time.Duration
+	checksumPolicy   ChecksumPolicy
+	outOfOrderPolicy OutOfOrderPolicy
+	table            tableName
+	deployID         string
 }
 
-func NewMigrator(db *pgx.Conn, dir string) (*Migrator, error) {
-	return &Migrator{
-		db:    db,
-		model: newModel(db),
-		files: &files{directory: dir},
-	}, nil
+// NewMigrator creates a Migrator for the migrations in dir.
+func NewMigrator(db DB, dir string, opts ...Option) (*Migrator, error) {
+	return newMigrator(db, newDirectoryFiles(dir), opts...), nil
+}
+
+// NewMigratorFS creates a Migrator for the migrations at the root of fsys,
+// such as an embed.FS, so migrations can run without any files on disk.
+// Migrations cannot be created by a Migrator created this way.
+func NewMigratorFS(db DB, fsys fs.FS, opts ...Option) (*Migrator, error) {
+	return newMigrator(db, newFSFiles(fsys), opts...), nil
+}
+
+func newMigrator(db DB, files *files, opts ...Option) *Migrator {
+	m := &Migrator{
+		db:          db,
+		conn:        db,
+		files:       files,
+		lockKey:     defaultLockKey,
+		lockTimeout: DefaultLockTimeout,
+		table:       tableName{name: DefaultTableName},
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	m.model = m.modelFor(db)
+
+	return m
 }
 
 func (m *Migrator) InitDirectory() error {
@@ -35,32 +103,101 @@ func (m *Migrator) InitDirectory() error {
 }
 
 func (m *Migrator) Migrate(ctx context.Context) error {
-	tx, err := m.db.Begin(ctx)
+	return m.withLock(ctx, func() error {
+		return m.migrate(ctx, "")
+	})
+}
+
+// MigrateTo migrates pending migrations up to and including target, which
+// can be a migration's timestamp, its name, or a path to it.
+func (m *Migrator) MigrateTo(ctx context.Context, target string) error {
+	return m.withLock(ctx, func() error {
+		return m.migrate(ctx, target)
+	})
+}
+
+// migrate migrates pending migrations up to and including target, or every
+// pending migration when target is empty.
+func (m *Migrator) migrate(ctx context.Context, target string) error {
+	fmt.Println("running migrations")
+
+	err := m.upgradeBookkeeping(ctx)
 	if err != nil {
 		return err
 	}
 
-	fmt.Println("running migrations")
-
-	_, err = m.db.Exec(
-		ctx,
-		`
-			CREATE TABLE IF NOT EXISTS migrations (
-				migration_id varchar PRIMARY KEY,
-				migrated_at timestamptz DEFAULT NOW() NOT NULL,
-				last_applied_at timestamptz DEFAULT NOW() NOT NULL
-			);
-		`,
-	)
+	err = m.checkChecksums(ctx)
 	if err != nil {
 		return err
 	}
 
-	migrated, skipped := 0, 0
 	files, err := m.files.getMigrationFiles()
 	if err != nil {
 		return err
 	}
+
+	if target != "" {
+		files, err = filesUpTo(files, target)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = m.checkOrder(ctx, files)
+	if err != nil {
+		return err
+	}
+
+	if m.transactionMode == TransactionPerBatch {
+		var applied []string
+		err = m.inTransaction(ctx, func(tx pgx.Tx) error {
+			return m.migrateEach(ctx, files, func(name string) error {
+				directives, err := m.files.readDirectives(name)
+				if err != nil {
+					return err
+				}
+				if directives.noTransaction {
+					return fmt.Errorf(
+						"%s cannot run in a single transaction because it is marked %s",
+						name,
+						directiveNoTransaction,
+					)
+				}
+
+				applied = append(applied, name)
+				return m.apply(ctx, tx, name)
+			})
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, name := range applied {
+			m.model.remember(name)
+		}
+
+		return nil
+	}
+
+	return m.migrateEach(ctx, files, func(name string) error {
+		err := m.inMigrationTransaction(ctx, name, func(db querier) error {
+			return m.apply(ctx, db, name)
+		})
+		if err != nil {
+			return err
+		}
+
+		// apply marks the migration through another model, so the cache is
+		// updated for migrations that come later in the run
+		m.model.remember(name)
+
+		return nil
+	})
+}
+
+// migrateEach calls migrate for every file that has not been migrated yet.
+func (m *Migrator) migrateEach(ctx context.Context, files []string, migrate func(name string) error) error {
+	migrated, skipped := 0, 0
 	for _, name := range files {
 		isMigrated, err := m.model.IsMigrated(ctx, name)
 		if err != nil {
@@ -80,15 +217,7 @@ func (m *Migrator) Migrate(ctx context.Context) error {
 		}
 
 		fmt.Printf("running %s... ", name)
-		migrationPath := m.files.migrationPath(name)
-		err = runLua(ctx, m.db, runLuaConfig{file: migrationPath})
-		if err != nil {
-			return migrationFailed(
-				fmt.Errorf("migration %s failed: %s", name, err.Error()),
-			)
-		}
-
-		err = m.model.MarkAsMigrated(ctx, name)
+		err = migrate(name)
 		if err != nil {
 			return migrationFailed(err)
 		}
@@ -102,10 +231,142 @@ func (m *Migrator) Migrate(ctx context.Context) error {
 		fmt.Printf("skipped %d previously migrated migrations\n", skipped)
 	}
 
+	return nil
+}
+
+// apply runs a migration and marks it as migrated using db, which is
+// usually a transaction.
+func (m *Migrator) apply(ctx context.Context, db querier, name string) error {
+	checksum, err := m.files.checksum(name)
+	if err != nil {
+		return err
+	}
+
+	started := time.Now()
+	err = runMigration(ctx, db, m.files.fsys, name, false)
+	if err != nil {
+		return fmt.Errorf("migration %s failed: %s", name, err.Error())
+	}
+
+	return m.modelFor(db).MarkAsMigrated(ctx, name, m.newRun(checksum, started))
+}
+
+// inMigrationTransaction calls fn with a new transaction, or with the
+// connection itself when the migration opted out of transactions so it is
+// only marked as migrated once every statement succeeded.
+func (m *Migrator) inMigrationTransaction(ctx context.Context, name string, fn func(db querier) error) error {
+	directives, err := m.files.readDirectives(name)
+	if err != nil {
+		return err
+	}
+
+	if directives.noTransaction {
+		return fn(m.conn)
+	}
+
+	return m.inTransaction(ctx, func(tx pgx.Tx) error {
+		return fn(tx)
+	})
+}
+
+// WithTable sets the name of the table migrations are recorded in; the
+// default is DefaultTableName.
+func WithTable(name string) Option {
+	return func(m *Migrator) {
+		m.table.name = name
+	}
+}
+
+// WithSchema sets the schema of the table migrations are recorded in, which
+// is created if it does not exist; by default the table is created in the
+// first schema of the search_path.
+func WithSchema(schema string) Option {
+	return func(m *Migrator) {
+		m.table.schema = schema
+	}
+}
+
+func (m *Migrator) modelFor(db querier) *model {
+	return newModel(db, m.table)
+}
+
+// upgradeBookkeeping creates or upgrades the migrations table in its own
+// transaction; the advisory lock must be held.
+func (m *Migrator) upgradeBookkeeping(ctx context.Context) error {
+	return m.inTransaction(ctx, func(tx pgx.Tx) error {
+		return m.modelFor(tx).Upgrade(ctx)
+	})
+}
+
+// ensureBookkeeping upgrades an outdated migrations table for commands that
+// only read it and so do not otherwise hold the advisory lock. It returns
+// false when the migrations table does not exist.
+func (m *Migrator) ensureBookkeeping(ctx context.Context) (bool, error) {
+	exists, err := m.model.TableExists(ctx)
+	if err != nil || !exists {
+		return false, err
+	}
+
+	upToDate, err := m.model.IsUpToDate(ctx)
+	if err != nil || upToDate {
+		return true, err
+	}
+
+	return true, m.withLock(ctx, func() error {
+		return m.upgradeBookkeeping(ctx)
+	})
+}
+
+// withConn calls fn with m.conn set to a connection acquired from the pool
+// when db is a pool, so advisory locks, session settings and transactions
+// used during a run all share one session.
+func (m *Migrator) withConn(ctx context.Context, fn func() error) error {
+	pool, isPool := m.db.(*pgxpool.Pool)
+	if !isPool {
+		return fn()
+	}
+
+	conn, err := pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	m.conn, m.model = conn, m.modelFor(conn)
+	defer func() {
+		m.conn, m.model = m.db, m.modelFor(m.db)
+	}()
+
+	return fn()
+}
+
+// inTransaction calls fn with a new transaction that is committed when fn
+// succeeds and rolled back when it fails.
+func (m *Migrator) inTransaction(ctx context.Context, fn func(tx pgx.Tx) error) error {
+	tx, err := m.conn.Begin(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = fn(tx)
+	if err != nil {
+		return errors.Join(err, tx.Rollback(ctx))
+	}
+
 	return tx.Commit(ctx)
 }
 
+// Create creates a new Lua migration from the directory's template.
 func (m *Migrator) Create(name string) error {
+	return m.create(name, extLua)
+}
+
+// CreateSQL creates a new .sql migration from the directory's template.
+func (m *Migrator) CreateSQL(name string) error {
+	return m.create(name, extSQL)
+}
+
+func (m *Migrator) create(name string, ext string) error {
 	if err := m.files.validateDirectory(); err != nil {
 		return err
 	}
@@ -118,7 +379,7 @@ func (m *Migrator) Create(name string) error {
 		)
 	}
 
-	err := m.files.createNewMigrationFile(name)
+	err := m.files.createNewMigrationFile(name, ext)
 	if err != nil {
 		return err
 	}
@@ -127,20 +388,24 @@ func (m *Migrator) Create(name string) error {
 }
 
 func (m *Migrator) Reapply(ctx context.Context, name string) error {
+	return m.withLock(ctx, func() error {
+		return m.reapply(ctx, name)
+	})
+}
+
+func (m *Migrator) reapply(ctx context.Context, name string) error {
 	if err := m.files.validateDirectory(); err != nil {
 		return err
 	}
 
-	migrationName := path.Base(name)
-	if !strings.HasSuffix(migrationName, ".lua") {
-		migrationName = migrationName + ".lua"
+	migrationName, err := m.files.resolveName(name)
+	if err != nil {
+		return err
 	}
 
-	if !regexpMigMatchFileName.MatchString(migrationName) {
-		return fmt.Errorf(
-			"migration file %s does not exist",
-			migrationName,
-		)
+	err = m.upgradeBookkeeping(ctx)
+	if err != nil {
+		return err
 	}
 
 	isMigrated, err := m.model.IsMigrated(ctx, migrationName)
@@ -157,28 +422,50 @@ func (m *Migrator) Reapply(ctx context.Context, name string) error {
 		)
 	}
 
-	tx, err := m.db.Begin(ctx)
+	checksum, err := m.files.checksum(migrationName)
 	if err != nil {
 		return err
 	}
-	migrationPath := m.files.migrationPath(migrationName)
-	err = runLua(ctx, m.db, runLuaConfig{
-		file: migrationPath,
-	})
-	if err != nil {
-		return migrationFailed(
-			fmt.Errorf("migration %s failed: %s", migrationName, err.Error()),
-		)
-	}
 
-	err = m.model.MarkAsReapplied(ctx, name)
+	fmt.Printf("reapplying %s... ", migrationName)
+	err = m.inMigrationTransaction(ctx, migrationName, func(db querier) error {
+		started := time.Now()
+		err := runMigration(ctx, db, m.files.fsys, migrationName, false)
+		if err != nil {
+			return fmt.Errorf("migration %s failed: %s", migrationName, err.Error())
+		}
+
+		return m.modelFor(db).MarkAsReapplied(ctx, migrationName, m.newRun(checksum, started))
+	})
 	if err != nil {
 		return migrationFailed(err)
 	}
 
 	fmt.Println("done")
 
-	return tx.Commit(ctx)
+	return nil
+}
+
+// filesUpTo returns the migration files up to and including target, which
+// can be a migration's timestamp, its name, or a path to it.
+func filesUpTo(files []string, target string) ([]string, error) {
+	target = path.Base(target)
+	for i, name := range files {
+		if matchesName(name, target) {
+			return files[:i+1], nil
+		}
+	}
+
+	return nil, fmt.Errorf("migration %s does not exist", target)
+}
+
+// runMigration runs a migration file with the runner for its extension.
+func runMigration(ctx context.Context, db luapgx.Querier, fsys fs.FS, file string, rollback bool) error {
+	if path.Ext(file) == extSQL {
+		return runSQL(ctx, db, runSQLConfig{file: file, fsys: fsys, rollback: rollback})
+	}
+
+	return runLua(ctx, db, runLuaConfig{file: file, fsys: fsys, rollback: rollback})
 }
 
 func migrationFailed(err error) error {
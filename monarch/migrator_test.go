@@ -2,15 +2,22 @@ package monarch
 
 import (
 	"context"
+	"embed"
 	"errors"
 	"fmt"
+	"io/fs"
 	"os"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+//go:embed test/working_migrations
+var embeddedMigrations embed.FS
+
 func getManagementConnection(ctx context.Context) (*pgx.Conn, error) {
 	databaseURL := os.Getenv("DATABASE_URL")
 	if databaseURL == "" {
@@ -89,3 +96,943 @@ func TestMigrationsCanRunSuccessfully(t *testing.T) {
 		t.Fatalf("expected 'no rows error'; got: %s", err)
 	}
 }
+
+func TestStatusListsPendingAndAppliedMigrations(t *testing.T) {
+	ctx := context.Background()
+	db, _, cleanup, err := getTestConnection(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanup(ctx)
+
+	migrator, err := NewMigrator(db, "./test/working_migrations")
+	if err != nil {
+		t.Fatalf("error setting up migrator: %s", err)
+	}
+
+	statuses, err := migrator.Status(ctx)
+	if err != nil {
+		t.Fatalf("error getting status: %s", err)
+	}
+	if len(statuses) != 1 || statuses[0].State != MigrationPending {
+		t.Fatalf("expected a single pending migration; got %+v", statuses)
+	}
+
+	err = migrator.Migrate(ctx)
+	if err != nil {
+		t.Fatalf("error running migrations: %s", err)
+	}
+
+	statuses, err = migrator.Status(ctx)
+	if err != nil {
+		t.Fatalf("error getting status: %s", err)
+	}
+	if len(statuses) != 1 || statuses[0].State != MigrationApplied {
+		t.Fatalf("expected a single applied migration; got %+v", statuses)
+	}
+	if statuses[0].MigratedAt.IsZero() {
+		t.Fatalf("expected applied migration to have a migrated at time")
+	}
+}
+
+func TestRollbackRunsDownFunctionsNewestFirst(t *testing.T) {
+	ctx := context.Background()
+	db, assertDB, cleanup, err := getTestConnection(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanup(ctx)
+
+	migrator, err := NewMigrator(db, "./test/reversible_migrations")
+	if err != nil {
+		t.Fatalf("error setting up migrator: %s", err)
+	}
+
+	err = migrator.Migrate(ctx)
+	if err != nil {
+		t.Fatalf("error running migrations: %s", err)
+	}
+
+	err = migrator.Rollback(ctx, 1)
+	if err != nil {
+		t.Fatalf("error rolling back migrations: %s", err)
+	}
+
+	_, err = assertDB.Exec(ctx, "SELECT name FROM test_table")
+	if err == nil {
+		t.Fatalf("expected name column to be dropped")
+	}
+
+	err = migrator.RollbackTo(ctx, "20240107135800_CreateTable")
+	if err != nil {
+		t.Fatalf("error rolling back migrations: %s", err)
+	}
+
+	if tableExists(ctx, t, assertDB, "test_table") {
+		t.Fatalf("expected test_table to be dropped")
+	}
+
+	var migrated int
+	err = assertDB.QueryRow(ctx, "SELECT COUNT(*) FROM migrations").Scan(&migrated)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if migrated != 0 {
+		t.Fatalf("expected no migrations to remain migrated; got %d", migrated)
+	}
+}
+
+func TestRollbackUndoesTheLastMigrationApplied(t *testing.T) {
+	ctx := context.Background()
+	db, assertDB, cleanup, err := getTestConnection(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanup(ctx)
+
+	migrator, err := NewMigrator(db, "./test/independent_migrations", WithOutOfOrderPolicy(OutOfOrderAllow))
+	if err != nil {
+		t.Fatalf("error setting up migrator: %s", err)
+	}
+
+	err = migrator.Migrate(ctx)
+	if err != nil {
+		t.Fatalf("error running migrations: %s", err)
+	}
+
+	// apply the older migration again, after the newer one
+	_, err = assertDB.Exec(ctx, `
+		DROP TABLE first_table;
+		DELETE FROM migrations WHERE migration_id = '20240107135800_CreateFirstTable.lua';
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a new migrator, since the first one remembers what it migrated
+	migrator, err = NewMigrator(db, "./test/independent_migrations", WithOutOfOrderPolicy(OutOfOrderAllow))
+	if err != nil {
+		t.Fatalf("error setting up migrator: %s", err)
+	}
+
+	err = migrator.Migrate(ctx)
+	if err != nil {
+		t.Fatalf("error running migrations: %s", err)
+	}
+
+	err = migrator.Rollback(ctx, 1)
+	if err != nil {
+		t.Fatalf("error rolling back migrations: %s", err)
+	}
+
+	if tableExists(ctx, t, assertDB, "first_table") {
+		t.Fatalf("expected the last migration applied to be rolled back")
+	}
+	if !tableExists(ctx, t, assertDB, "second_table") {
+		t.Fatalf("expected the migration with the newest name to remain migrated")
+	}
+
+	err = migrator.RollbackTo(ctx, "20240107135900")
+	if err != nil {
+		t.Fatalf("error rolling back to a timestamp: %s", err)
+	}
+
+	if tableExists(ctx, t, assertDB, "second_table") {
+		t.Fatalf("expected the migration with the timestamp to be rolled back")
+	}
+}
+
+func TestRollbackFailsWithoutDownFunction(t *testing.T) {
+	ctx := context.Background()
+	db, assertDB, cleanup, err := getTestConnection(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanup(ctx)
+
+	migrator, err := NewMigrator(db, "./test/working_migrations")
+	if err != nil {
+		t.Fatalf("error setting up migrator: %s", err)
+	}
+
+	err = migrator.Migrate(ctx)
+	if err != nil {
+		t.Fatalf("error running migrations: %s", err)
+	}
+
+	err = migrator.Rollback(ctx, 1)
+	if err == nil {
+		t.Fatalf("expected rolling back a migration without a down function to fail")
+	}
+
+	if !tableExists(ctx, t, assertDB, "test_table") {
+		t.Fatalf("expected test_table to still exist")
+	}
+}
+
+func tableExists(ctx context.Context, t *testing.T, db *pgx.Conn, table string) bool {
+	t.Helper()
+
+	var exists bool
+	err := db.QueryRow(ctx, "SELECT to_regclass($1) IS NOT NULL", table).Scan(&exists)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return exists
+}
+
+func TestFailedMigrationIsRolledBack(t *testing.T) {
+	ctx := context.Background()
+	db, assertDB, cleanup, err := getTestConnection(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanup(ctx)
+
+	migrator, err := NewMigrator(db, "./test/failing_migrations")
+	if err != nil {
+		t.Fatalf("error setting up migrator: %s", err)
+	}
+
+	err = migrator.Migrate(ctx)
+	if err == nil {
+		t.Fatalf("expected migrations to fail")
+	}
+
+	if !tableExists(ctx, t, assertDB, "test_table") {
+		t.Fatalf("expected migration before the failing migration to be committed")
+	}
+	if tableExists(ctx, t, assertDB, "other_test_table") {
+		t.Fatalf("expected failing migration to be rolled back")
+	}
+}
+
+func TestFailedMigrationRollsBackBatch(t *testing.T) {
+	ctx := context.Background()
+	db, assertDB, cleanup, err := getTestConnection(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanup(ctx)
+
+	migrator, err := NewMigrator(db, "./test/failing_migrations", WithTransactionMode(TransactionPerBatch))
+	if err != nil {
+		t.Fatalf("error setting up migrator: %s", err)
+	}
+
+	err = migrator.Migrate(ctx)
+	if err == nil {
+		t.Fatalf("expected migrations to fail")
+	}
+
+	if tableExists(ctx, t, assertDB, "test_table") {
+		t.Fatalf("expected every migration in the batch to be rolled back")
+	}
+	if tableExists(ctx, t, assertDB, "other_test_table") {
+		t.Fatalf("expected failing migration to be rolled back")
+	}
+}
+
+func TestNoTransactionMigrationRunsOutsideTransaction(t *testing.T) {
+	ctx := context.Background()
+	db, assertDB, cleanup, err := getTestConnection(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanup(ctx)
+
+	migrator, err := NewMigrator(db, "./test/no_transaction_migrations")
+	if err != nil {
+		t.Fatalf("error setting up migrator: %s", err)
+	}
+
+	err = migrator.Migrate(ctx)
+	if err != nil {
+		t.Fatalf("error running migrations: %s", err)
+	}
+
+	if !tableExists(ctx, t, assertDB, "test_table_id_idx") {
+		t.Fatalf("expected index to be created concurrently")
+	}
+}
+
+func TestNoTransactionMigrationCannotRunInBatch(t *testing.T) {
+	ctx := context.Background()
+	db, assertDB, cleanup, err := getTestConnection(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanup(ctx)
+
+	migrator, err := NewMigrator(db, "./test/no_transaction_migrations", WithTransactionMode(TransactionPerBatch))
+	if err != nil {
+		t.Fatalf("error setting up migrator: %s", err)
+	}
+
+	err = migrator.Migrate(ctx)
+	if err == nil {
+		t.Fatalf("expected migrations to fail")
+	}
+
+	if tableExists(ctx, t, assertDB, "test_table") {
+		t.Fatalf("expected every migration in the batch to be rolled back")
+	}
+}
+
+func TestMigrateWaitsForLock(t *testing.T) {
+	ctx := context.Background()
+	db, assertDB, cleanup, err := getTestConnection(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanup(ctx)
+
+	_, err = assertDB.Exec(ctx, "SELECT pg_advisory_lock($1)", defaultLockKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	migrator, err := NewMigrator(db, "./test/working_migrations", WithLockTimeout(2*time.Second))
+	if err != nil {
+		t.Fatalf("error setting up migrator: %s", err)
+	}
+
+	err = migrator.Migrate(ctx)
+	if err == nil {
+		t.Fatalf("expected migrations to time out waiting for the lock")
+	}
+	if tableExists(ctx, t, assertDB, "test_table") {
+		t.Fatalf("expected migrations not to run without the lock")
+	}
+
+	_, err = assertDB.Exec(ctx, "SELECT pg_advisory_unlock($1)", defaultLockKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = migrator.Migrate(ctx)
+	if err != nil {
+		t.Fatalf("error running migrations: %s", err)
+	}
+}
+
+func TestChangedMigrationFailsVerification(t *testing.T) {
+	ctx := context.Background()
+	db, assertDB, cleanup, err := getTestConnection(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanup(ctx)
+
+	migrator, err := NewMigrator(db, "./test/working_migrations")
+	if err != nil {
+		t.Fatalf("error setting up migrator: %s", err)
+	}
+
+	err = migrator.Migrate(ctx)
+	if err != nil {
+		t.Fatalf("error running migrations: %s", err)
+	}
+
+	problems, err := migrator.Verify(ctx)
+	if err != nil {
+		t.Fatalf("error verifying migrations: %s", err)
+	}
+	if len(problems) != 0 {
+		t.Fatalf("expected no verification problems; got %+v", problems)
+	}
+
+	_, err = assertDB.Exec(ctx, "UPDATE migrations SET checksum = 'changed'")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	problems, err = migrator.Verify(ctx)
+	if err != nil {
+		t.Fatalf("error verifying migrations: %s", err)
+	}
+	if len(problems) != 1 || problems[0].Problem != VerificationModified {
+		t.Fatalf("expected a single modified migration; got %+v", problems)
+	}
+
+	err = migrator.Migrate(ctx)
+	if err == nil {
+		t.Fatalf("expected migrating with a changed migration to fail")
+	}
+
+	warnMigrator, err := NewMigrator(db, "./test/working_migrations", WithChecksumPolicy(ChecksumWarn))
+	if err != nil {
+		t.Fatalf("error setting up migrator: %s", err)
+	}
+
+	err = warnMigrator.Migrate(ctx)
+	if err != nil {
+		t.Fatalf("expected migrating with a changed migration to only warn; got %s", err)
+	}
+}
+
+func TestSQLMigrationsRunAndRollBack(t *testing.T) {
+	ctx := context.Background()
+	db, assertDB, cleanup, err := getTestConnection(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanup(ctx)
+
+	migrator, err := NewMigrator(db, "./test/sql_migrations")
+	if err != nil {
+		t.Fatalf("error setting up migrator: %s", err)
+	}
+
+	err = migrator.Migrate(ctx)
+	if err != nil {
+		t.Fatalf("error running migrations: %s", err)
+	}
+
+	var name string
+	err = assertDB.QueryRow(ctx, "SELECT test_table_name(5)").Scan(&name)
+	if err != nil {
+		t.Fatalf("expected function to be created: %s", err)
+	}
+	if name != "name; 5" {
+		t.Fatalf("expected function to return %q; got %q", "name; 5", name)
+	}
+
+	err = migrator.Rollback(ctx, 1)
+	if err != nil {
+		t.Fatalf("error rolling back migrations: %s", err)
+	}
+
+	_, err = assertDB.Exec(ctx, "SELECT name FROM test_table")
+	if err == nil {
+		t.Fatalf("expected name column to be dropped")
+	}
+}
+
+func TestMigrationsCanRunFromFS(t *testing.T) {
+	ctx := context.Background()
+	db, assertDB, cleanup, err := getTestConnection(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanup(ctx)
+
+	migrations, err := fs.Sub(embeddedMigrations, "test/working_migrations")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	migrator, err := NewMigratorFS(db, migrations)
+	if err != nil {
+		t.Fatalf("error setting up migrator: %s", err)
+	}
+
+	err = migrator.Migrate(ctx)
+	if err != nil {
+		t.Fatalf("error running migrations: %s", err)
+	}
+
+	if !tableExists(ctx, t, assertDB, "test_table") {
+		t.Fatalf("expected embedded migration to create test_table")
+	}
+
+	err = migrator.Create("not_allowed")
+	if !errors.Is(err, errReadOnlyFS) {
+		t.Fatalf("expected creating a migration in an fs.FS to fail; got %v", err)
+	}
+}
+
+func TestMigrationsCanRunWithPool(t *testing.T) {
+	ctx := context.Background()
+	db, assertDB, cleanup, err := getTestConnection(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanup(ctx)
+
+	poolConfig, err := pgxpool.ParseConfig("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	poolConfig.ConnConfig = db.Config()
+
+	pool, err := pgxpool.NewWithConfig(ctx, poolConfig)
+	if err != nil {
+		t.Fatalf("error creating pool: %s", err)
+	}
+	defer pool.Close()
+
+	migrator, err := NewMigrator(pool, "./test/working_migrations")
+	if err != nil {
+		t.Fatalf("error setting up migrator: %s", err)
+	}
+
+	err = migrator.Migrate(ctx)
+	if err != nil {
+		t.Fatalf("error running migrations: %s", err)
+	}
+
+	if !tableExists(ctx, t, assertDB, "test_table") {
+		t.Fatalf("expected migration to create test_table")
+	}
+
+	statuses, err := migrator.Status(ctx)
+	if err != nil {
+		t.Fatalf("error getting status: %s", err)
+	}
+	if len(statuses) != 1 || statuses[0].State != MigrationApplied {
+		t.Fatalf("expected a single applied migration; got %+v", statuses)
+	}
+}
+
+func TestMigrateToStopsAtTarget(t *testing.T) {
+	ctx := context.Background()
+	db, assertDB, cleanup, err := getTestConnection(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanup(ctx)
+
+	migrator, err := NewMigrator(db, "./test/reversible_migrations")
+	if err != nil {
+		t.Fatalf("error setting up migrator: %s", err)
+	}
+
+	err = migrator.MigrateTo(ctx, "20240107135000")
+	if err == nil {
+		t.Fatalf("expected migrating to a migration that does not exist to fail")
+	}
+
+	err = migrator.MigrateTo(ctx, "20240107135800")
+	if err != nil {
+		t.Fatalf("error running migrations: %s", err)
+	}
+
+	if !tableExists(ctx, t, assertDB, "test_table") {
+		t.Fatalf("expected target migration to run")
+	}
+	_, err = assertDB.Exec(ctx, "SELECT name FROM test_table")
+	if err == nil {
+		t.Fatalf("expected migration after the target not to run")
+	}
+
+	// the same migrator continues where the staged rollout stopped
+	err = migrator.MigrateTo(ctx, "20240107135800")
+	if err != nil {
+		t.Fatalf("expected migrating to the same target again to do nothing; got %s", err)
+	}
+
+	err = migrator.Migrate(ctx)
+	if err != nil {
+		t.Fatalf("error running the remaining migrations: %s", err)
+	}
+
+	_, err = assertDB.Exec(ctx, "SELECT name FROM test_table")
+	if err != nil {
+		t.Fatalf("expected migration after the target to run: %s", err)
+	}
+}
+
+func TestMigrateSkipsMigrationsAppliedByAnotherMigrator(t *testing.T) {
+	ctx := context.Background()
+	db, assertDB, cleanup, err := getTestConnection(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanup(ctx)
+
+	migrator, err := NewMigrator(db, "./test/reversible_migrations")
+	if err != nil {
+		t.Fatalf("error setting up migrator: %s", err)
+	}
+
+	err = migrator.MigrateTo(ctx, "20240107135800")
+	if err != nil {
+		t.Fatalf("error running migrations: %s", err)
+	}
+
+	// another pod migrates the next migration
+	other, err := NewMigrator(assertDB, "./test/reversible_migrations")
+	if err != nil {
+		t.Fatalf("error setting up migrator: %s", err)
+	}
+
+	err = other.Migrate(ctx)
+	if err != nil {
+		t.Fatalf("error running migrations with another migrator: %s", err)
+	}
+
+	err = migrator.Migrate(ctx)
+	if err != nil {
+		t.Fatalf("expected migrations applied by another migrator to be skipped; got %s", err)
+	}
+}
+
+func TestPlanRecordsStatementsWithoutMigrating(t *testing.T) {
+	ctx := context.Background()
+	db, assertDB, cleanup, err := getTestConnection(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanup(ctx)
+
+	migrator, err := NewMigrator(db, "./test/sql_migrations")
+	if err != nil {
+		t.Fatalf("error setting up migrator: %s", err)
+	}
+
+	planned, err := migrator.Plan(ctx)
+	if err != nil {
+		t.Fatalf("error planning migrations: %s", err)
+	}
+
+	if len(planned) != 2 {
+		t.Fatalf("expected 2 planned migrations; got %d", len(planned))
+	}
+	if len(planned[0].Statements) != 1 || !strings.Contains(planned[0].Statements[0].SQL, "CREATE TABLE test_table") {
+		t.Fatalf("expected first migration to create test_table; got %+v", planned[0].Statements)
+	}
+	if len(planned[1].Statements) != 2 {
+		t.Fatalf("expected second migration to run 2 statements; got %+v", planned[1].Statements)
+	}
+
+	if tableExists(ctx, t, assertDB, "test_table") {
+		t.Fatalf("expected planned migrations to be rolled back")
+	}
+	if tableExists(ctx, t, assertDB, "migrations") {
+		t.Fatalf("expected migrations table to be rolled back")
+	}
+}
+
+func TestOutOfOrderMigrationsAreDetected(t *testing.T) {
+	ctx := context.Background()
+	db, assertDB, cleanup, err := getTestConnection(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanup(ctx)
+
+	migrator, err := NewMigrator(db, "./test/reversible_migrations")
+	if err != nil {
+		t.Fatalf("error setting up migrator: %s", err)
+	}
+
+	err = migrator.Migrate(ctx)
+	if err != nil {
+		t.Fatalf("error running migrations: %s", err)
+	}
+
+	// forget the older migration as if it was merged after the newer one ran
+	_, err = assertDB.Exec(ctx, "DELETE FROM migrations WHERE migration_id = '20240107135800_CreateTable.lua'")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	errorMigrator, err := NewMigrator(db, "./test/reversible_migrations", WithOutOfOrderPolicy(OutOfOrderError))
+	if err != nil {
+		t.Fatalf("error setting up migrator: %s", err)
+	}
+
+	err = errorMigrator.Migrate(ctx)
+	if err == nil || !strings.Contains(err.Error(), "20240107135800_CreateTable.lua") {
+		t.Fatalf("expected out of order migration to be reported; got %v", err)
+	}
+}
+
+func TestMigrationsTableCanBeConfigured(t *testing.T) {
+	ctx := context.Background()
+	db, assertDB, cleanup, err := getTestConnection(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanup(ctx)
+
+	migrator, err := NewMigrator(
+		db,
+		"./test/working_migrations",
+		WithSchema("monarch"),
+		WithTable("Schema Migrations"),
+	)
+	if err != nil {
+		t.Fatalf("error setting up migrator: %s", err)
+	}
+
+	err = migrator.Migrate(ctx)
+	if err != nil {
+		t.Fatalf("error running migrations: %s", err)
+	}
+
+	if tableExists(ctx, t, assertDB, "migrations") {
+		t.Fatalf("expected default migrations table not to be created")
+	}
+	if !tableExists(ctx, t, assertDB, `monarch."Schema Migrations"`) {
+		t.Fatalf("expected configured migrations table to be created")
+	}
+
+	statuses, err := migrator.Status(ctx)
+	if err != nil {
+		t.Fatalf("error getting status: %s", err)
+	}
+	if len(statuses) != 1 || statuses[0].State != MigrationApplied {
+		t.Fatalf("expected a single applied migration; got %+v", statuses)
+	}
+}
+
+func TestMigrationMetadataIsRecorded(t *testing.T) {
+	ctx := context.Background()
+	db, _, cleanup, err := getTestConnection(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanup(ctx)
+
+	migrator, err := NewMigrator(db, "./test/working_migrations", WithDeployID("build-42"))
+	if err != nil {
+		t.Fatalf("error setting up migrator: %s", err)
+	}
+
+	err = migrator.Migrate(ctx)
+	if err != nil {
+		t.Fatalf("error running migrations: %s", err)
+	}
+
+	err = migrator.Reapply(ctx, "20240107135800_CreateTable")
+	if err == nil {
+		t.Fatalf("expected reapplying a migration that creates a table to fail")
+	}
+
+	statuses, err := migrator.Status(ctx)
+	if err != nil {
+		t.Fatalf("error getting status: %s", err)
+	}
+	if len(statuses) != 1 {
+		t.Fatalf("expected a single migration; got %+v", statuses)
+	}
+
+	status := statuses[0]
+	if status.DeployID != "build-42" {
+		t.Fatalf("expected deploy ID %q; got %q", "build-42", status.DeployID)
+	}
+	if status.AppliedBy != db.Config().User {
+		t.Fatalf("expected applied by %q; got %q", db.Config().User, status.AppliedBy)
+	}
+	if status.MonarchVersion == "" {
+		t.Fatalf("expected monarch version to be recorded")
+	}
+	if status.ReapplyCount != 0 {
+		t.Fatalf("expected failed reapply not to be counted; got %d", status.ReapplyCount)
+	}
+}
+
+func TestOldMigrationsTableIsUpgraded(t *testing.T) {
+	ctx := context.Background()
+	db, assertDB, cleanup, err := getTestConnection(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanup(ctx)
+
+	// the layout of the migrations table before monarch versioned it
+	_, err = assertDB.Exec(ctx, `
+		CREATE TABLE migrations (
+			migration_id varchar PRIMARY KEY,
+			migrated_at timestamptz DEFAULT NOW() NOT NULL,
+			last_applied_at timestamptz DEFAULT NOW() NOT NULL
+		);
+		INSERT INTO migrations (migration_id) VALUES ('20240107135800_CreateTable.lua');
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	migrator, err := NewMigrator(db, "./test/working_migrations")
+	if err != nil {
+		t.Fatalf("error setting up migrator: %s", err)
+	}
+
+	statuses, err := migrator.Status(ctx)
+	if err != nil {
+		t.Fatalf("error getting status: %s", err)
+	}
+	if len(statuses) != 1 || statuses[0].State != MigrationApplied {
+		t.Fatalf("expected a single applied migration; got %+v", statuses)
+	}
+	if statuses[0].AppliedBy != "" {
+		t.Fatalf("expected a migration recorded before the upgrade to have no AppliedBy; got %q", statuses[0].AppliedBy)
+	}
+
+	var version int
+	err = assertDB.QueryRow(ctx, "SELECT version FROM migrations_meta").Scan(&version)
+	if err != nil {
+		t.Fatalf("expected version to be recorded: %s", err)
+	}
+	if version != len(bookkeepingSteps) {
+		t.Fatalf("expected version %d; got %d", len(bookkeepingSteps), version)
+	}
+
+	_, err = assertDB.Exec(ctx, "UPDATE migrations_meta SET version = version + 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = migrator.Migrate(ctx)
+	if err == nil || !strings.Contains(err.Error(), "newer version of monarch") {
+		t.Fatalf("expected migrating with a newer migrations table to fail; got %v", err)
+	}
+}
+
+func TestBaselineMarksMigrationsWithoutRunningThem(t *testing.T) {
+	ctx := context.Background()
+	db, assertDB, cleanup, err := getTestConnection(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanup(ctx)
+
+	migrator, err := NewMigrator(db, "./test/reversible_migrations")
+	if err != nil {
+		t.Fatalf("error setting up migrator: %s", err)
+	}
+
+	// the schema built by another tool
+	_, err = assertDB.Exec(ctx, "CREATE TABLE test_table (id SERIAL NOT NULL PRIMARY KEY)")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = migrator.Baseline(ctx, "20240107135800_CreateTable")
+	if err != nil {
+		t.Fatalf("error baselining migrations: %s", err)
+	}
+
+	err = migrator.Migrate(ctx)
+	if err != nil {
+		t.Fatalf("error running migrations: %s", err)
+	}
+
+	statuses, err := migrator.Status(ctx)
+	if err != nil {
+		t.Fatalf("error getting status: %s", err)
+	}
+	if len(statuses) != 2 {
+		t.Fatalf("expected 2 migrations; got %+v", statuses)
+	}
+	if statuses[0].State != MigrationApplied || statuses[0].AppliedVia != AppliedViaBaseline {
+		t.Fatalf("expected first migration to be baselined; got %+v", statuses[0])
+	}
+	if statuses[1].State != MigrationApplied || statuses[1].AppliedVia != AppliedViaMigrate {
+		t.Fatalf("expected second migration to be migrated; got %+v", statuses[1])
+	}
+}
+
+func TestMarkAppliedAndPendingAreAudited(t *testing.T) {
+	ctx := context.Background()
+	db, assertDB, cleanup, err := getTestConnection(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanup(ctx)
+
+	migrator, err := NewMigrator(db, "./test/working_migrations")
+	if err != nil {
+		t.Fatalf("error setting up migrator: %s", err)
+	}
+
+	err = migrator.MarkPending(ctx, "20240107135800_CreateTable")
+	if err == nil {
+		t.Fatalf("expected marking a pending migration as pending to fail")
+	}
+
+	err = migrator.MarkApplied(ctx, "20240107135800_CreateTable")
+	if err != nil {
+		t.Fatalf("error marking migration as applied: %s", err)
+	}
+
+	err = migrator.Migrate(ctx)
+	if err != nil {
+		t.Fatalf("error running migrations: %s", err)
+	}
+	if tableExists(ctx, t, assertDB, "test_table") {
+		t.Fatalf("expected migration marked as applied not to run")
+	}
+
+	err = migrator.MarkPending(ctx, "20240107135800_CreateTable")
+	if err != nil {
+		t.Fatalf("error marking migration as pending: %s", err)
+	}
+
+	err = migrator.Migrate(ctx)
+	if err != nil {
+		t.Fatalf("error running migrations: %s", err)
+	}
+	if !tableExists(ctx, t, assertDB, "test_table") {
+		t.Fatalf("expected migration marked as pending to run")
+	}
+
+	rows, err := assertDB.Query(ctx, "SELECT action, db_user FROM migrations_audit ORDER BY audit_id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	actions, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) ([2]string, error) {
+		var action [2]string
+		err := row.Scan(&action[0], &action[1])
+		return action, err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	user := db.Config().User
+	expected := [][2]string{{"mark-applied", user}, {"mark-pending", user}}
+	if fmt.Sprint(actions) != fmt.Sprint(expected) {
+		t.Fatalf("expected audit trail %v; got %v", expected, actions)
+	}
+}
+
+func TestRedoRollsBackAndMigratesAgain(t *testing.T) {
+	ctx := context.Background()
+	db, assertDB, cleanup, err := getTestConnection(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanup(ctx)
+
+	migrator, err := NewMigrator(db, "./test/reversible_migrations")
+	if err != nil {
+		t.Fatalf("error setting up migrator: %s", err)
+	}
+
+	err = migrator.Migrate(ctx)
+	if err != nil {
+		t.Fatalf("error running migrations: %s", err)
+	}
+
+	_, err = assertDB.Exec(ctx, "INSERT INTO test_table (name) VALUES ('before redo')")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = migrator.Redo(ctx, 2)
+	if err != nil {
+		t.Fatalf("error redoing migrations: %s", err)
+	}
+
+	var count int
+	err = assertDB.QueryRow(ctx, "SELECT COUNT(name) FROM test_table").Scan(&count)
+	if err != nil {
+		t.Fatalf("expected redone migrations to recreate test_table: %s", err)
+	}
+	if count != 0 {
+		t.Fatalf("expected test_table to be recreated empty; got %d rows", count)
+	}
+
+	var migrated int
+	err = assertDB.QueryRow(ctx, "SELECT COUNT(*) FROM migrations").Scan(&migrated)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if migrated != 2 {
+		t.Fatalf("expected 2 migrations to be migrated; got %d", migrated)
+	}
+}
@@ -2,17 +2,97 @@ package monarch
 
 import (
 	"context"
+	"fmt"
+	"time"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 )
 
+type querier interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+type migrationRecord struct {
+	id            string
+	migratedAt    time.Time
+	lastAppliedAt time.Time
+	// checksum is empty for migrations migrated before checksums were recorded
+	checksum string
+	// the remaining fields are empty for migrations migrated before they
+	// were recorded
+	duration       time.Duration
+	appliedBy      string
+	hostname       string
+	monarchVersion string
+	deployID       string
+	reapplyCount   int
+	appliedVia     AppliedVia
+}
+
+// migrationRun is what is recorded about a run of a migration.
+type migrationRun struct {
+	appliedVia     AppliedVia
+	checksum       string
+	duration       time.Duration
+	hostname       string
+	monarchVersion string
+	deployID       string
+}
+
+// DefaultTableName is the name of the table migrations are recorded in.
+const DefaultTableName = "migrations"
+
+// tableName is the optionally schema qualified name of the migrations table.
+type tableName struct {
+	schema string
+	name   string
+}
+
+// meta returns the name of the table monarch records the version of its own
+// tables in.
+func (t tableName) meta() tableName {
+	return tableName{schema: t.schema, name: t.name + "_meta"}
+}
+
+// audit returns the name of the table changes made to the migrations table
+// by hand are recorded in.
+func (t tableName) audit() tableName {
+	return tableName{schema: t.schema, name: t.name + "_audit"}
+}
+
+// sanitize returns the table name quoted for use in SQL.
+func (t tableName) sanitize() string {
+	if t.schema == "" {
+		return pgx.Identifier{t.name}.Sanitize()
+	}
+
+	return pgx.Identifier{t.schema, t.name}.Sanitize()
+}
+
 type model struct {
-	db             *pgx.Conn
+	db             querier
+	table          tableName
 	migratedByName map[string]bool
 }
 
-func newModel(db *pgx.Conn) *model {
-	return &model{db: db}
+func newModel(db querier, table tableName) *model {
+	return &model{db: db, table: table}
+}
+
+func (model *model) TableExists(ctx context.Context) (bool, error) {
+	var exists bool
+	err := model.db.QueryRow(
+		ctx,
+		`
+			SELECT to_regclass($1) IS NOT NULL
+		`,
+		model.table.sanitize(),
+	).Scan(&exists)
+
+	return exists, err
 }
 
 func (model *model) IsMigrated(ctx context.Context, id string) (bool, error) {
@@ -31,42 +111,144 @@ func (model *model) IsMigrated(ctx context.Context, id string) (bool, error) {
 	return ran, nil
 }
 
-func (model *model) MarkAsMigrated(ctx context.Context, id string) error {
+func (model *model) MarkAsMigrated(ctx context.Context, id string, run migrationRun) error {
 	_, err := model.db.Exec(
 		ctx,
-		`
-			INSERT INTO migrations (migration_id)
-			VALUES ($1)
-		`,
+		fmt.Sprintf(
+			`
+				INSERT INTO %s (
+					migration_id,
+					checksum,
+					duration_ms,
+					applied_by,
+					hostname,
+					monarch_version,
+					deploy_id,
+					applied_via
+				)
+				VALUES ($1, $2, $3, current_user, $4, $5, NULLIF($6, ''), $7)
+			`,
+			model.table.sanitize(),
+		),
 		id,
+		run.checksum,
+		run.duration.Milliseconds(),
+		run.hostname,
+		run.monarchVersion,
+		run.deployID,
+		run.appliedVia,
 	)
 
 	return err
 }
 
-func (model *model) MarkAsReapplied(ctx context.Context, id string) error {
+func (model *model) MarkAsReapplied(ctx context.Context, id string, run migrationRun) error {
 	_, err := model.db.Exec(
 		ctx,
-		`
-			UPDATE migrations
-			SET last_applied_at = NOW()
-			WHERE migration_id = $1
-		`,
+		fmt.Sprintf(
+			`
+				UPDATE %s
+				SET
+					last_applied_at = NOW(),
+					checksum = $2,
+					duration_ms = $3,
+					applied_by = current_user,
+					hostname = $4,
+					monarch_version = $5,
+					deploy_id = NULLIF($6, ''),
+					reapply_count = reapply_count + 1
+				WHERE migration_id = $1
+			`,
+			model.table.sanitize(),
+		),
 		id,
+		run.checksum,
+		run.duration.Milliseconds(),
+		run.hostname,
+		run.monarchVersion,
+		run.deployID,
 	)
 
 	return err
 }
 
+func (model *model) MarkAsRolledBack(ctx context.Context, id string) error {
+	_, err := model.db.Exec(
+		ctx,
+		fmt.Sprintf(
+			`
+				DELETE FROM %s
+				WHERE migration_id = $1
+			`,
+			model.table.sanitize(),
+		),
+		id,
+	)
+
+	return err
+}
+
+// Audit records a change to the migrations table made by hand, by osUser.
+func (model *model) Audit(ctx context.Context, id string, action string, osUser string, run migrationRun) error {
+	_, err := model.db.Exec(
+		ctx,
+		fmt.Sprintf(
+			`
+				INSERT INTO %s (
+					migration_id,
+					action,
+					os_user,
+					hostname,
+					monarch_version,
+					deploy_id
+				)
+				VALUES ($1, $2, NULLIF($3, ''), $4, $5, NULLIF($6, ''))
+			`,
+			model.table.audit().sanitize(),
+		),
+		id,
+		action,
+		osUser,
+		run.hostname,
+		run.monarchVersion,
+		run.deployID,
+	)
+
+	return err
+}
+
+// reset drops the cache of migrated migrations, so it is loaded again from
+// the migrations table, which other runs may have changed.
+func (model *model) reset() {
+	model.migratedByName = nil
+}
+
+// forget drops a migration from the cache after it was rolled back through
+// another model, such as one bound to a transaction.
+func (model *model) forget(id string) {
+	delete(model.migratedByName, id)
+}
+
+// remember adds a migration to the cache after it was marked as migrated
+// through another model, such as one bound to a transaction.
+func (model *model) remember(id string) {
+	if model.migratedByName != nil {
+		model.migratedByName[id] = true
+	}
+}
+
 func (model *model) loadMigratedByName(ctx context.Context) error {
 	model.migratedByName = make(map[string]bool)
 
 	rows, err := model.db.Query(
 		ctx,
-		`
-			SELECT migration_id
-			FROM migrations
-		`,
+		fmt.Sprintf(
+			`
+				SELECT migration_id
+				FROM %s
+			`,
+			model.table.sanitize(),
+		),
 	)
 	if err != nil {
 		return err
@@ -84,3 +266,59 @@ func (model *model) loadMigratedByName(ctx context.Context) error {
 
 	return nil
 }
+
+func (model *model) List(ctx context.Context) ([]migrationRecord, error) {
+	rows, err := model.db.Query(
+		ctx,
+		fmt.Sprintf(
+			`
+				SELECT
+					migration_id,
+					migrated_at,
+					last_applied_at,
+					COALESCE(checksum, ''),
+					COALESCE(duration_ms, 0),
+					COALESCE(applied_by, ''),
+					COALESCE(hostname, ''),
+					COALESCE(monarch_version, ''),
+					COALESCE(deploy_id, ''),
+					reapply_count,
+					applied_via
+				FROM %s
+				ORDER BY migration_id
+			`,
+			model.table.sanitize(),
+		),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var records []migrationRecord
+	for rows.Next() {
+		var record migrationRecord
+		var durationMS int64
+		err = rows.Scan(
+			&record.id,
+			&record.migratedAt,
+			&record.lastAppliedAt,
+			&record.checksum,
+			&durationMS,
+			&record.appliedBy,
+			&record.hostname,
+			&record.monarchVersion,
+			&record.deployID,
+			&record.reapplyCount,
+			&record.appliedVia,
+		)
+		if err != nil {
+			return nil, err
+		}
+		record.duration = time.Duration(durationMS) * time.Millisecond
+
+		records = append(records, record)
+	}
+
+	return records, rows.Err()
+}
@@ -0,0 +1,75 @@
+package monarch
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+type OutOfOrderPolicy int
+
+const (
+	// OutOfOrderWarn prints pending migrations that are older than the newest
+	// migrated migration and migrates them anyway.
+	OutOfOrderWarn OutOfOrderPolicy = iota
+	// OutOfOrderError refuses to migrate when a pending migration is older
+	// than the newest migrated migration.
+	OutOfOrderError
+	// OutOfOrderAllow migrates out of order migrations without reporting them.
+	OutOfOrderAllow
+)
+
+// WithOutOfOrderPolicy sets what Migrate does with pending migrations that
+// are older than the newest migrated migration, which usually come from
+// branches merged after newer migrations were deployed; the default is
+// OutOfOrderWarn.
+func WithOutOfOrderPolicy(policy OutOfOrderPolicy) Option {
+	return func(m *Migrator) {
+		m.outOfOrderPolicy = policy
+	}
+}
+
+// checkOrder applies the out of order policy to the pending files in files.
+func (m *Migrator) checkOrder(ctx context.Context, files []string) error {
+	if m.outOfOrderPolicy == OutOfOrderAllow {
+		return nil
+	}
+
+	records, err := m.model.List(ctx)
+	if err != nil || len(records) == 0 {
+		return err
+	}
+
+	migrated := make(map[string]bool, len(records))
+	for _, record := range records {
+		migrated[record.id] = true
+	}
+
+	// records are ordered by name, so the last one is the newest
+	newest := records[len(records)-1].id
+
+	var outOfOrder []string
+	for _, name := range files {
+		if !migrated[name] && name < newest {
+			outOfOrder = append(outOfOrder, name)
+		}
+	}
+
+	if len(outOfOrder) == 0 {
+		return nil
+	}
+
+	if m.outOfOrderPolicy == OutOfOrderWarn {
+		for _, name := range outOfOrder {
+			fmt.Printf("warning: %s is older than the newest migrated migration %s\n", name, newest)
+		}
+
+		return nil
+	}
+
+	return fmt.Errorf(
+		"pending migrations are older than the newest migrated migration %s: %s; rename them with newer timestamps or allow out of order migrations",
+		newest,
+		strings.Join(outOfOrder, ", "),
+	)
+}
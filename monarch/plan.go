@@ -0,0 +1,121 @@
+package monarch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+
+	"github.com/tinyprint/monarch/monarch/internal/luapgx"
+)
+
+type PlannedStatement struct {
+	SQL  string
+	Args []any
+}
+
+type PlannedMigration struct {
+	Name       string
+	Statements []PlannedStatement
+	// NotRun is set for Lua migrations that opted out of transactions; they
+	// cannot be rolled back, so they are not run and their SQL is unknown.
+	NotRun bool
+}
+
+// Plan runs every pending migration in a transaction that is always rolled
+// back, recording each statement the migrations execute so the SQL can be
+// reviewed before it is migrated.
+func (m *Migrator) Plan(ctx context.Context) ([]PlannedMigration, error) {
+	var planned []PlannedMigration
+	err := m.withLock(ctx, func() error {
+		var err error
+		planned, err = m.plan(ctx)
+		return err
+	})
+
+	return planned, err
+}
+
+func (m *Migrator) plan(ctx context.Context) (planned []PlannedMigration, err error) {
+	files, err := m.files.getMigrationFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := m.conn.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		err = errors.Join(err, tx.Rollback(ctx))
+	}()
+
+	model := m.modelFor(tx)
+	err = model.Upgrade(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, name := range files {
+		isMigrated, err := model.IsMigrated(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		if isMigrated {
+			continue
+		}
+
+		directives, err := m.files.readDirectives(name)
+		if err != nil {
+			return nil, err
+		}
+
+		if directives.noTransaction {
+			migration, err := m.planWithoutRunning(name)
+			if err != nil {
+				return nil, err
+			}
+			planned = append(planned, migration)
+			continue
+		}
+
+		recorder := luapgx.NewRecorder(tx)
+		err = runMigration(ctx, recorder, m.files.fsys, name, false)
+		if err != nil {
+			return nil, fmt.Errorf("migration %s failed: %s", name, err.Error())
+		}
+
+		migration := PlannedMigration{Name: name}
+		for _, statement := range recorder.Statements {
+			migration.Statements = append(migration.Statements, PlannedStatement{
+				SQL:  statement.SQL,
+				Args: statement.Args,
+			})
+		}
+		planned = append(planned, migration)
+	}
+
+	return planned, nil
+}
+
+// planWithoutRunning plans a migration that opted out of transactions, which
+// is only possible for .sql migrations since their statements are known
+// without running them.
+func (m *Migrator) planWithoutRunning(name string) (PlannedMigration, error) {
+	migration := PlannedMigration{Name: name}
+	if path.Ext(name) != extSQL {
+		migration.NotRun = true
+		return migration, nil
+	}
+
+	statements, err := readSQLStatements(runSQLConfig{file: name, fsys: m.files.fsys})
+	if err != nil {
+		return migration, err
+	}
+
+	for _, statement := range statements {
+		migration.Statements = append(migration.Statements, PlannedStatement{SQL: statement})
+	}
+
+	return migration, nil
+}
@@ -0,0 +1,89 @@
+package monarch
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Redo rolls back the last steps migrations, newest first, then migrates
+// them again, oldest first. Everything runs in one transaction unless one of
+// the migrations opted out of transactions.
+func (m *Migrator) Redo(ctx context.Context, steps int) error {
+	return m.withLock(ctx, func() error {
+		return m.redo(ctx, steps)
+	})
+}
+
+func (m *Migrator) redo(ctx context.Context, steps int) error {
+	names, err := m.lastMigrated(ctx, steps)
+	if err != nil {
+		return err
+	}
+
+	err = m.requireFiles(names)
+	if err != nil {
+		return err
+	}
+
+	singleTransaction := true
+	for _, name := range names {
+		directives, err := m.files.readDirectives(name)
+		if err != nil {
+			return err
+		}
+		if directives.noTransaction {
+			singleTransaction = false
+		}
+	}
+
+	if !singleTransaction {
+		return m.redoEach(ctx, names)
+	}
+
+	return m.inTransaction(ctx, func(tx pgx.Tx) error {
+		for _, name := range names {
+			fmt.Printf("rolling back %s... ", name)
+			err := m.unapply(ctx, tx, name)
+			if err != nil {
+				return migrationFailed(err)
+			}
+			fmt.Println("done")
+		}
+
+		for i := len(names) - 1; i >= 0; i-- {
+			fmt.Printf("running %s... ", names[i])
+			err := m.apply(ctx, tx, names[i])
+			if err != nil {
+				return migrationFailed(err)
+			}
+			fmt.Println("done")
+		}
+
+		return nil
+	})
+}
+
+// redoEach redoes migrations one transaction at a time, for when a migration
+// opted out of transactions and they cannot all run in one.
+func (m *Migrator) redoEach(ctx context.Context, names []string) error {
+	err := m.rollback(ctx, names)
+	if err != nil {
+		return err
+	}
+
+	for i := len(names) - 1; i >= 0; i-- {
+		fmt.Printf("running %s... ", names[i])
+		err = m.inMigrationTransaction(ctx, names[i], func(db querier) error {
+			return m.apply(ctx, db, names[i])
+		})
+		if err != nil {
+			return migrationFailed(err)
+		}
+		m.model.remember(names[i])
+		fmt.Println("done")
+	}
+
+	return nil
+}
@@ -0,0 +1,184 @@
+package monarch
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+)
+
+// Rollback runs the down function of the last steps migrations, newest first,
+// and removes them from the migrations table.
+func (m *Migrator) Rollback(ctx context.Context, steps int) error {
+	return m.withLock(ctx, func() error {
+		return m.rollbackSteps(ctx, steps)
+	})
+}
+
+func (m *Migrator) rollbackSteps(ctx context.Context, steps int) error {
+	names, err := m.lastMigrated(ctx, steps)
+	if err != nil {
+		return err
+	}
+
+	return m.rollback(ctx, names)
+}
+
+// lastMigrated returns the names of the last steps migrated migrations,
+// newest first.
+func (m *Migrator) lastMigrated(ctx context.Context, steps int) ([]string, error) {
+	if steps < 1 {
+		return nil, fmt.Errorf("cannot roll back %d migrations", steps)
+	}
+
+	err := m.upgradeBookkeeping(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	records, err := m.model.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	records = newestFirst(records)
+
+	if steps > len(records) {
+		return nil, fmt.Errorf(
+			"cannot roll back %d migrations; only %d have been migrated",
+			steps,
+			len(records),
+		)
+	}
+
+	names := make([]string, 0, steps)
+	for _, record := range records[:steps] {
+		names = append(names, record.id)
+	}
+
+	return names, nil
+}
+
+// newestFirst returns a copy of records ordered by when they were migrated,
+// newest first, since migrations applied out of order were migrated after
+// migrations with later names.
+func newestFirst(records []migrationRecord) []migrationRecord {
+	sorted := slices.Clone(records)
+	slices.SortStableFunc(sorted, func(a, b migrationRecord) int {
+		if c := b.migratedAt.Compare(a.migratedAt); c != 0 {
+			return c
+		}
+
+		return strings.Compare(b.id, a.id)
+	})
+
+	return sorted
+}
+
+// RollbackTo rolls back every migration migrated after name, and name itself,
+// newest first.
+func (m *Migrator) RollbackTo(ctx context.Context, name string) error {
+	return m.withLock(ctx, func() error {
+		return m.rollbackTo(ctx, name)
+	})
+}
+
+func (m *Migrator) rollbackTo(ctx context.Context, name string) error {
+	target := path.Base(name)
+
+	err := m.upgradeBookkeeping(ctx)
+	if err != nil {
+		return err
+	}
+
+	records, err := m.model.List(ctx)
+	if err != nil {
+		return err
+	}
+
+	var names []string
+	for _, record := range newestFirst(records) {
+		names = append(names, record.id)
+		if matchesName(record.id, target) {
+			return m.rollback(ctx, names)
+		}
+	}
+
+	return fmt.Errorf(
+		"cannot roll back %s because it has not been migrated",
+		target,
+	)
+}
+
+func (m *Migrator) rollback(ctx context.Context, names []string) error {
+	err := m.requireFiles(names)
+	if err != nil {
+		return err
+	}
+
+	for _, name := range names {
+		fmt.Printf("rolling back %s... ", name)
+		err = m.rollbackOne(ctx, name)
+		if err != nil {
+			return migrationFailed(err)
+		}
+
+		fmt.Println("done")
+	}
+
+	return nil
+}
+
+// requireFiles returns an error when a migration being rolled back no longer
+// has a file on disk to roll it back with.
+func (m *Migrator) requireFiles(names []string) error {
+	if err := m.files.validateDirectory(); err != nil {
+		return err
+	}
+
+	files, err := m.files.getMigrationFiles()
+	if err != nil {
+		return err
+	}
+
+	onDisk := make(map[string]bool, len(files))
+	for _, name := range files {
+		onDisk[name] = true
+	}
+
+	for _, name := range names {
+		if !onDisk[name] {
+			return fmt.Errorf(
+				"cannot roll back %s because its file does not exist",
+				name,
+			)
+		}
+	}
+
+	return nil
+}
+
+func (m *Migrator) rollbackOne(ctx context.Context, name string) error {
+	err := m.inMigrationTransaction(ctx, name, func(db querier) error {
+		return m.unapply(ctx, db, name)
+	})
+	if err != nil {
+		return err
+	}
+
+	m.model.forget(name)
+
+	return nil
+}
+
+// unapply rolls back a migration and removes it from the migrations table
+// using db, which is usually a transaction.
+func (m *Migrator) unapply(ctx context.Context, db querier, name string) error {
+	err := runMigration(ctx, db, m.files.fsys, name, true)
+	if err != nil {
+		return fmt.Errorf("rolling back migration %s failed: %s", name, err.Error())
+	}
+
+	return m.modelFor(db).MarkAsRolledBack(ctx, name)
+}
@@ -0,0 +1,78 @@
+package monarch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+
+	"github.com/tinyprint/monarch/monarch/internal/luapgx"
+	"github.com/tinyprint/monarch/monarch/internal/sqlscan"
+)
+
+type runSQLConfig struct {
+	// file is read from fsys, or from the local filesystem when fsys is nil
+	file     string
+	fsys     fs.FS
+	rollback bool
+}
+
+// runSQL runs each statement of a .sql migration separately, so statements
+// that cannot run in a multi-statement query, like CREATE INDEX CONCURRENTLY,
+// work in migrations that opt out of transactions.
+func runSQL(ctx context.Context, db luapgx.Querier, config runSQLConfig) error {
+	statements, err := readSQLStatements(config)
+	if err != nil {
+		return err
+	}
+
+	for i, statement := range statements {
+		_, err = db.Exec(ctx, statement)
+		if err != nil {
+			return errors.Join(fmt.Errorf("statement %d failed", i+1), err)
+		}
+	}
+
+	return nil
+}
+
+// readSQLStatements reads the statements runSQL would run.
+func readSQLStatements(config runSQLConfig) ([]string, error) {
+	var contents []byte
+	var err error
+	if config.fsys == nil {
+		contents, err = os.ReadFile(config.file)
+	} else {
+		contents, err = fs.ReadFile(config.fsys, config.file)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	up, down, hasDown := splitSQLMigration(string(contents))
+	script := up
+	if config.rollback {
+		if !hasDown {
+			return nil, fmt.Errorf("migration does not define a -- %s section", directiveDown)
+		}
+		script = down
+	}
+
+	return sqlscan.Split(script), nil
+}
+
+// splitSQLMigration splits a .sql migration at its -- monarch:down line into
+// the script that migrates and the script that rolls back.
+func splitSQLMigration(contents string) (string, string, bool) {
+	lines := strings.SplitAfter(contents, "\n")
+	for i, line := range lines {
+		comment, isComment := strings.CutPrefix(strings.TrimSpace(line), "--")
+		if isComment && strings.TrimSpace(comment) == directiveDown {
+			return strings.Join(lines[:i], ""), strings.Join(lines[i+1:], ""), true
+		}
+	}
+
+	return contents, "", false
+}
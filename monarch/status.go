@@ -0,0 +1,111 @@
+package monarch
+
+import (
+	"context"
+	"sort"
+	"time"
+)
+
+type MigrationState string
+
+const (
+	// MigrationApplied is a migration that has a file on disk and has been migrated.
+	MigrationApplied MigrationState = "applied"
+	// MigrationPending is a migration that has a file on disk but has not been migrated yet.
+	MigrationPending MigrationState = "pending"
+	// MigrationMissing is a migration that has been migrated but no longer has a file on disk.
+	MigrationMissing MigrationState = "missing"
+)
+
+type MigrationStatus struct {
+	Name  string
+	State MigrationState
+	// MigratedAt, LastAppliedAt and the fields after them are zero for
+	// pending migrations, and for migrations migrated before monarch
+	// recorded them.
+	MigratedAt    time.Time
+	LastAppliedAt time.Time
+	// Duration is how long the last run of the migration took.
+	Duration time.Duration
+	// AppliedBy is the database user the migration was last run as.
+	AppliedBy string
+	// Hostname is the host monarch last ran the migration from.
+	Hostname       string
+	MonarchVersion string
+	DeployID       string
+	ReapplyCount   int
+	// AppliedVia distinguishes migrations that ran from ones that were
+	// recorded as migrated without running, such as by Baseline.
+	AppliedVia AppliedVia
+}
+
+// Status lists every migration known to the migrations directory or the
+// migrations table, ordered by name.
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	files, err := m.files.getMigrationFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	exists, err := m.ensureBookkeeping(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var records []migrationRecord
+	if exists {
+		records, err = m.model.List(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	recordsByName := make(map[string]migrationRecord, len(records))
+	for _, record := range records {
+		recordsByName[record.id] = record
+	}
+
+	statuses := make([]MigrationStatus, 0, len(files)+len(records))
+	onDisk := make(map[string]bool, len(files))
+	for _, name := range files {
+		onDisk[name] = true
+
+		record, migrated := recordsByName[name]
+		if !migrated {
+			statuses = append(statuses, MigrationStatus{Name: name, State: MigrationPending})
+			continue
+		}
+
+		statuses = append(statuses, newMigrationStatus(record, MigrationApplied))
+	}
+
+	for _, record := range records {
+		if onDisk[record.id] {
+			continue
+		}
+
+		statuses = append(statuses, newMigrationStatus(record, MigrationMissing))
+	}
+
+	sort.SliceStable(statuses, func(i, j int) bool {
+		return statuses[i].Name < statuses[j].Name
+	})
+
+	return statuses, nil
+}
+
+func newMigrationStatus(record migrationRecord, state MigrationState) MigrationStatus {
+	return MigrationStatus{
+		Name:           record.id,
+		State:          state,
+		MigratedAt:     record.migratedAt,
+		LastAppliedAt:  record.lastAppliedAt,
+		Duration:       record.duration,
+		AppliedBy:      record.appliedBy,
+		Hostname:       record.hostname,
+		MonarchVersion: record.monarchVersion,
+		DeployID:       record.deployID,
+		ReapplyCount:   record.reapplyCount,
+		AppliedVia:     record.appliedVia,
+	}
+}
@@ -0,0 +1,7 @@
+-- 20240107135800_CreateTable
+
+db.exec([===[
+    CREATE TABLE test_table (
+        id SERIAL NOT NULL PRIMARY KEY
+    )
+]===]);
@@ -0,0 +1,9 @@
+-- 20240107135900_CreateTableThenFail
+
+db.exec([===[
+    CREATE TABLE other_test_table (
+        id SERIAL NOT NULL PRIMARY KEY
+    )
+]===]);
+
+error("migration failed on purpose")
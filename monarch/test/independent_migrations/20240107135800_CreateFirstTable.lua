@@ -0,0 +1,15 @@
+-- 20240107135800_CreateFirstTable
+
+function up()
+    db.exec([===[
+        CREATE TABLE first_table (
+            id SERIAL NOT NULL PRIMARY KEY
+        )
+    ]===]);
+end
+
+function down()
+    db.exec([===[
+        DROP TABLE first_table
+    ]===]);
+end
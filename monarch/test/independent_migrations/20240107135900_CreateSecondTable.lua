@@ -0,0 +1,15 @@
+-- 20240107135900_CreateSecondTable
+
+function up()
+    db.exec([===[
+        CREATE TABLE second_table (
+            id SERIAL NOT NULL PRIMARY KEY
+        )
+    ]===]);
+end
+
+function down()
+    db.exec([===[
+        DROP TABLE second_table
+    ]===]);
+end
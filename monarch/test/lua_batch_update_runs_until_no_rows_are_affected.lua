@@ -0,0 +1,61 @@
+db.exec([===[
+    CREATE TEMPORARY TABLE lua_batch_update_test (id integer PRIMARY KEY, done boolean NOT NULL DEFAULT false)
+]===], {});
+db.exec([===[
+    INSERT INTO lua_batch_update_test (id) SELECT generate_series(1, 25)
+]===], {});
+
+-- LIMIT based batches select the rows that are left to update
+local total = db.batch_update{
+    sql = [===[
+        UPDATE lua_batch_update_test SET done = true
+        WHERE id IN (SELECT id FROM lua_batch_update_test WHERE NOT done LIMIT $1)
+    ]===],
+    batch_size = 10,
+}
+assert(total == 25, "LIMIT based batches updated " .. total .. " rows")
+
+-- keyed batches continue after the greatest key of the previous batch
+local last
+total, last = db.batch_update{
+    sql = [===[
+        UPDATE lua_batch_update_test SET done = false
+        WHERE id IN (SELECT id FROM lua_batch_update_test WHERE id > $2 ORDER BY id LIMIT $1)
+        RETURNING id
+    ]===],
+    batch_size = 10,
+    after = 5,
+}
+assert(total == 20, "keyed batches updated " .. total .. " rows")
+assert(last == 25, "keyed batches ended at key " .. tostring(last))
+
+-- max_batches stops a backfill with rows left to update
+local ok, err = pcall(db.batch_update, {
+    sql = [===[
+        UPDATE lua_batch_update_test SET done = true
+        WHERE id IN (SELECT id FROM lua_batch_update_test WHERE NOT done LIMIT $1)
+    ]===],
+    batch_size = 5,
+    max_batches = 2,
+})
+assert(not ok and string.find(err, "stopped after 2 batches"), "max_batches did not stop the backfill")
+assert(db.scalar("SELECT count(*) FROM lua_batch_update_test WHERE done", {}) == 15, "batches before max_batches were not committed")
+
+-- max_batches does not fail a backfill whose last allowed batch was smaller
+-- than batch_size, since it updated the last rows
+total = db.batch_update{
+    sql = [===[
+        UPDATE lua_batch_update_test SET done = true
+        WHERE id IN (SELECT id FROM lua_batch_update_test WHERE NOT done LIMIT $1)
+    ]===],
+    batch_size = 6,
+    max_batches = 2,
+}
+assert(total == 10, "batches up to max_batches updated " .. total .. " rows")
+assert(db.scalar("SELECT count(*) FROM lua_batch_update_test WHERE NOT done", {}) == 0, "rows were left to update")
+
+-- batches cannot run inside a transaction
+ok, err = pcall(db.transaction, function()
+    db.batch_update{sql = "SELECT 1 LIMIT $1"}
+end)
+assert(not ok and string.find(err, "no%-transaction"), "batch_update ran inside a transaction")
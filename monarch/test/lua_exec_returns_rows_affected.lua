@@ -0,0 +1,17 @@
+local affected, tag = db.exec([===[
+    CREATE TEMPORARY TABLE lua_exec_test (value integer)
+]===], {});
+assert(affected == 0, "CREATE TABLE affected " .. affected .. " rows")
+assert(tag == "CREATE TABLE", "unexpected command tag " .. tag)
+
+affected, tag = db.exec([===[
+    INSERT INTO lua_exec_test SELECT generate_series(1, 5)
+]===], {});
+assert(affected == 5, "INSERT affected " .. affected .. " rows")
+assert(tag == "INSERT 0 5", "unexpected command tag " .. tag)
+
+affected, tag = db.exec([===[
+    UPDATE lua_exec_test SET value = value * 10 WHERE value > $1
+]===], {3});
+assert(affected == 2, "UPDATE affected " .. affected .. " rows")
+assert(tag == "UPDATE 2", "unexpected command tag " .. tag)
@@ -0,0 +1,68 @@
+db.exec([===[
+    CREATE TEMPORARY TABLE lua_params_test (
+        smallint smallint,
+        integer integer,
+        bigint bigint,
+        numeric numeric,
+        double double precision,
+        boolean boolean,
+        text text,
+        nullable text,
+        integers integer[],
+        texts text[],
+        jsonb jsonb,
+        json json
+    )
+]===], {});
+
+db.exec([===[
+    INSERT INTO lua_params_test VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+]===], {
+    32767,
+    2147483647,
+    9007199254740992,
+    1234.5,
+    0.25,
+    true,
+    "text",
+    db.null,
+    {1, 2, 3},
+    {"a", "b"},
+    {a = 1, b = {true, "c"}},
+    {"x", 2},
+});
+
+local row = db.query_one([===[
+    SELECT
+        smallint,
+        integer,
+        bigint,
+        numeric::varchar AS numeric,
+        double,
+        boolean,
+        text,
+        nullable IS NULL AS is_null,
+        array_to_string(integers, ',') AS integers,
+        array_to_string(texts, ',') AS texts,
+        jsonb->>'a' AS jsonb_a,
+        jsonb->'b'->>1 AS jsonb_b,
+        json->>0 AS json_0
+    FROM lua_params_test
+]===], {});
+
+assert(row.smallint == 32767, "smallint")
+assert(row.integer == 2147483647, "integer")
+assert(row.bigint == 9007199254740992, "bigint")
+assert(row.numeric == "1234.5", "numeric: " .. tostring(row.numeric))
+assert(row.double == 0.25, "double")
+assert(row.boolean == true, "boolean")
+assert(row.text == "text", "text")
+assert(row.is_null == true, "nil was not encoded as NULL")
+assert(row.integers == "1,2,3", "integer array: " .. tostring(row.integers))
+assert(row.texts == "a,b", "text array: " .. tostring(row.texts))
+assert(row.jsonb_a == "1", "jsonb object: " .. tostring(row.jsonb_a))
+assert(row.jsonb_b == "c", "jsonb nested array: " .. tostring(row.jsonb_b))
+assert(row.json_0 == "x", "json array: " .. tostring(row.json_0))
+
+-- integer valued numbers can be compared with integer columns
+assert(db.scalar("SELECT count(*) FROM lua_params_test WHERE integer = :value", {value = 2147483647}) == 1)
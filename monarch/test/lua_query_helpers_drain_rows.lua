@@ -0,0 +1,31 @@
+local row = db.query_one([===[
+    SELECT 1 AS id, 'one' AS name
+]===], {});
+assert(row.id == 1 and row.name == "one", "query_one returned the wrong row")
+assert(row[1] == 1 and row[2] == "one", "query_one row is not indexed by position")
+
+local ok, err = pcall(db.query_one, [===[
+    SELECT 1 WHERE false
+]===], {});
+assert(not ok and string.find(err, "no rows"), "query_one did not fail without rows")
+
+ok, err = pcall(db.query_one, [===[
+    SELECT generate_series(1, 2)
+]===], {});
+assert(not ok and string.find(err, "more than one row"), "query_one did not fail with several rows")
+
+local rows = db.query_all([===[
+    SELECT generate_series(1, 3) AS n
+]===], {});
+assert(#rows == 3, "query_all returned " .. #rows .. " rows")
+for i, row in ipairs(rows) do
+    assert(row.n == i, "query_all returned rows out of order")
+end
+assert(#db.query_all("SELECT 1 WHERE false", {}) == 0, "query_all returned rows for an empty query")
+
+assert(db.scalar("SELECT count(*) FROM generate_series(1, 5)", {}) == 5, "scalar returned the wrong value")
+assert(db.scalar("SELECT 1 WHERE false", {}) == nil, "scalar returned a value for an empty query")
+assert(db.scalar("SELECT generate_series(1, 3)", {}) == 1, "scalar did not return the first row")
+
+-- every helper closed its rows, so the connection is not busy
+assert(db.scalar("SELECT 'done'", {}) == "done")
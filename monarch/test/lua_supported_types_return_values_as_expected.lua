@@ -24,12 +24,12 @@ local result = db.query([===[
         'any amount of characters'::varchar AS varchar,
         'a lot of text'::text AS text,
 
-        '2023-12-26'::date::varchar AS date,
-        '5 years, 3 months, 12 days, 3 hours'::interval::varchar::varchar AS interval,
-        '19:23:53'::time::varchar::varchar AS time,
-        '19:23:53 EST'::timetz::varchar::varchar AS timetz,
-        '2023-12-26 19:23:53'::timestamp::varchar AS timestamp,
-        '2023-12-26 19:23:53 EST'::timestamptz::varchar AS timestamptz,
+        '2023-12-26'::date AS date,
+        '5 years, 3 months, 12 days, 3 hours'::interval AS interval,
+        '19:23:53'::time AS time,
+        '19:23:53 EST'::timetz AS timetz,
+        '2023-12-26 19:23:53'::timestamp AS timestamp,
+        '2023-12-26 19:23:53 EST'::timestamptz AS timestamptz,
 
         '{"field": "value"}' AS json,
         '{"field": "value"}' AS jsonb
@@ -64,12 +64,22 @@ local expected = {
     {name="varchar", value="any amount of characters"},
     {name="text", value="a lot of text"},
 
-    {name="date", value="2023-12-26"},
-    {name="interval", value="5 years 3 mons 12 days 03:00:00"},
-    {name="time", value="19:23:53"},
+    {name="date", value={
+        year=2023, month=12, day=26, hour=0, min=0, sec=0, wday=3, yday=360, isdst=false,
+        iso="2023-12-26",
+    }},
+    {name="interval", value={months=63, days=12, microseconds=10800000000, iso="P5Y3M12DT3H"}},
+    {name="time", value={hour=19, min=23, sec=53, iso="19:23:53"}},
     {name="timetz", value="19:23:53-05"},
-    {name="timestamp", value="2023-12-26 19:23:53"},
-    {name="timestamptz", value="2023-12-26 17:23:53-07"},
+    {name="timestamp", value={
+        year=2023, month=12, day=26, hour=19, min=23, sec=53, wday=3, yday=360, isdst=false,
+        iso="2023-12-26T19:23:53",
+    }},
+    -- timestamps with time zones are returned in UTC
+    {name="timestamptz", value={
+        year=2023, month=12, day=27, hour=0, min=23, sec=53, wday=4, yday=361, isdst=false,
+        iso="2023-12-27T00:23:53Z",
+    }},
 
     {name="json", value='{"field": "value"}'},
     {name="jsonb", value='{"field": "value"}'},
@@ -132,3 +142,31 @@ for row in result.rows do
         end
     end
 end
+
+-- dates, times, timestamps and intervals can be used as parameters again
+local temporal = db.query_one([===[
+    SELECT
+        '2023-12-26'::date AS date,
+        '5 years, 3 months, 12 days, 3 hours'::interval AS interval,
+        '19:23:53.25'::time AS time,
+        '2023-12-26 19:23:53.5'::timestamp AS timestamp,
+        '2023-12-26 19:23:53 EST'::timestamptz AS timestamptz
+]===], {});
+
+assert(tostring(temporal.time) == "19:23:53.25", "tostring did not return the iso field")
+
+local roundTripped = db.scalar([===[
+    SELECT
+        :date::date = '2023-12-26'::date
+        AND :interval::interval = '5 years, 3 months, 12 days, 3 hours'::interval
+        AND :time::time = '19:23:53.25'::time
+        AND :timestamp::timestamp = '2023-12-26 19:23:53.5'::timestamp
+        AND :timestamptz::timestamptz = '2023-12-26 19:23:53 EST'::timestamptz
+]===], {
+    date = temporal.date,
+    interval = temporal.interval,
+    time = temporal.time,
+    timestamp = temporal.timestamp,
+    timestamptz = temporal.timestamptz,
+});
+assert(roundTripped == true, "temporal values did not round trip as parameters")
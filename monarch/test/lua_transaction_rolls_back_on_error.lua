@@ -0,0 +1,54 @@
+db.exec([===[
+    CREATE TEMPORARY TABLE lua_transaction_test (value integer)
+]===], {});
+
+local function count()
+    for row in db.query([===[
+        SELECT count(*)::integer AS count FROM lua_transaction_test
+    ]===], {}).rows do
+        return row.count
+    end
+end
+
+-- a function that returns normally is committed
+db.transaction(function()
+    db.exec([===[
+        INSERT INTO lua_transaction_test VALUES (1)
+    ]===], {});
+end)
+assert(count() == 1, "committed transaction did not insert its row")
+
+-- a function that errors is rolled back and the error is raised again
+local ok, err = pcall(db.transaction, function()
+    db.exec([===[
+        INSERT INTO lua_transaction_test VALUES (2)
+    ]===], {});
+    error("expected failure")
+end)
+assert(not ok, "error in transaction was not raised again")
+assert(string.find(err, "expected failure"), "unexpected error: " .. tostring(err))
+assert(count() == 1, "failed transaction was not rolled back")
+
+-- a failing nested transaction rolls back to its savepoint only
+db.transaction(function()
+    db.exec([===[
+        INSERT INTO lua_transaction_test VALUES (3)
+    ]===], {});
+
+    local ok = pcall(db.transaction, function()
+        db.exec([===[
+            INSERT INTO lua_transaction_test VALUES (4)
+        ]===], {});
+        db.exec([===[
+            SELECT 1 / 0
+        ]===], {});
+    end)
+    assert(not ok, "failing statement in savepoint did not raise an error")
+end)
+assert(count() == 2, "savepoint was not rolled back on its own")
+
+-- the values returned by the function are returned by db.transaction
+local a, b = db.transaction(function()
+    return 1, "two"
+end)
+assert(a == 1 and b == "two", "values returned by the function were not returned")
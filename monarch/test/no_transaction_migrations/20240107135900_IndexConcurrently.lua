@@ -0,0 +1,6 @@
+-- 20240107135900_IndexConcurrently
+-- monarch:no-transaction
+
+db.exec([===[
+    CREATE INDEX CONCURRENTLY test_table_id_idx ON test_table (id)
+]===]);
@@ -0,0 +1,13 @@
+-- 20240107135900_AddName
+
+function up()
+    db.exec([===[
+        ALTER TABLE test_table ADD COLUMN name varchar
+    ]===]);
+end
+
+function down()
+    db.exec([===[
+        ALTER TABLE test_table DROP COLUMN name
+    ]===]);
+end
@@ -0,0 +1,15 @@
+-- 20240107135800_CreateTable
+
+function up()
+    db.exec([===[
+        CREATE TABLE test_table (
+            id SERIAL NOT NULL PRIMARY KEY
+        )
+    ]===]);
+end
+
+function down()
+    db.exec([===[
+        DROP TABLE test_table
+    ]===]);
+end
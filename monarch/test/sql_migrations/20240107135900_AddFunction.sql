@@ -0,0 +1,15 @@
+-- 20240107135900_AddFunction
+
+ALTER TABLE test_table ADD COLUMN name varchar;
+
+CREATE FUNCTION test_table_name(id int) RETURNS varchar AS $$
+    BEGIN
+        RETURN 'name; ' || id;
+    END;
+$$ LANGUAGE plpgsql;
+
+-- monarch:down
+
+DROP FUNCTION test_table_name(int);
+
+ALTER TABLE test_table DROP COLUMN name;
@@ -0,0 +1,143 @@
+package monarch
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+type ChecksumPolicy int
+
+const (
+	// ChecksumError refuses to migrate when a migrated file has changed.
+	ChecksumError ChecksumPolicy = iota
+	// ChecksumWarn prints the changed files and migrates anyway.
+	ChecksumWarn
+	// ChecksumIgnore migrates without comparing checksums.
+	ChecksumIgnore
+)
+
+// WithChecksumPolicy sets what Migrate does when a migrated file no longer
+// matches the checksum recorded when it was migrated; the default is
+// ChecksumError.
+func WithChecksumPolicy(policy ChecksumPolicy) Option {
+	return func(m *Migrator) {
+		m.checksumPolicy = policy
+	}
+}
+
+type VerificationProblem string
+
+const (
+	// VerificationModified is a migrated file that changed since it was migrated.
+	VerificationModified VerificationProblem = "modified"
+	// VerificationMissing is a migrated migration that no longer has a file on disk.
+	VerificationMissing VerificationProblem = "missing"
+	// VerificationUnknown is a migration migrated before checksums were
+	// recorded, so it cannot be verified.
+	VerificationUnknown VerificationProblem = "unknown"
+)
+
+type MigrationVerification struct {
+	Name    string
+	Problem VerificationProblem
+}
+
+// Verify compares every migrated migration against its file on disk and
+// returns the migrations that do not match, ordered by name.
+func (m *Migrator) Verify(ctx context.Context) ([]MigrationVerification, error) {
+	exists, err := m.ensureBookkeeping(ctx)
+	if err != nil || !exists {
+		return nil, err
+	}
+
+	records, err := m.model.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	files, err := m.files.getMigrationFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	onDisk := make(map[string]bool, len(files))
+	for _, name := range files {
+		onDisk[name] = true
+	}
+
+	var problems []MigrationVerification
+	for _, record := range records {
+		problem, err := m.verifyRecord(record, onDisk[record.id])
+		if err != nil {
+			return nil, err
+		}
+
+		if problem != "" {
+			problems = append(problems, MigrationVerification{
+				Name:    record.id,
+				Problem: problem,
+			})
+		}
+	}
+
+	return problems, nil
+}
+
+func (m *Migrator) verifyRecord(record migrationRecord, onDisk bool) (VerificationProblem, error) {
+	if !onDisk {
+		return VerificationMissing, nil
+	}
+
+	if record.checksum == "" {
+		return VerificationUnknown, nil
+	}
+
+	checksum, err := m.files.checksum(record.id)
+	if err != nil {
+		return "", err
+	}
+
+	if checksum != record.checksum {
+		return VerificationModified, nil
+	}
+
+	return "", nil
+}
+
+// checkChecksums applies the checksum policy to migrated files that changed
+// since they were migrated.
+func (m *Migrator) checkChecksums(ctx context.Context) error {
+	if m.checksumPolicy == ChecksumIgnore {
+		return nil
+	}
+
+	problems, err := m.Verify(ctx)
+	if err != nil {
+		return err
+	}
+
+	var modified []string
+	for _, problem := range problems {
+		if problem.Problem == VerificationModified {
+			modified = append(modified, problem.Name)
+		}
+	}
+
+	if len(modified) == 0 {
+		return nil
+	}
+
+	if m.checksumPolicy == ChecksumWarn {
+		for _, name := range modified {
+			fmt.Printf("warning: %s changed since it was migrated\n", name)
+		}
+
+		return nil
+	}
+
+	return fmt.Errorf(
+		"migrations changed since they were migrated: %s; restore the original files or use `monarch reapply`",
+		strings.Join(modified, ", "),
+	)
+}